@@ -5,17 +5,17 @@ go 1.19
 require (
 	github.com/Masterminds/sprig/v3 v3.2.3
 	github.com/google/go-jsonnet v0.19.1
-	github.com/hashicorp/go-version v1.6.0
+	github.com/hashicorp/go-version v1.7.0
 	github.com/hashicorp/hc-install v0.6.1
 	github.com/hashicorp/terraform-exec v0.19.0
-	github.com/hashicorp/terraform-json v0.18.0
+	github.com/hashicorp/terraform-json v0.24.0
 	github.com/hashicorp/terraform-registry-address v0.2.3
 	github.com/iancoleman/strcase v0.2.0
 	github.com/jsonnet-libs/k8s v0.0.0-20221208111239-5065a9c04841
 	github.com/onsi/gomega v1.24.1
 	github.com/spf13/cobra v1.6.1
 	github.com/spf13/pflag v1.0.5
-	github.com/zclconf/go-cty v1.14.1
+	github.com/zclconf/go-cty v1.15.1
 	go.uber.org/zap v1.24.0
 )
 
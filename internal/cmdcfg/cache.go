@@ -0,0 +1,72 @@
+package cmdcfg
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+	"github.com/tf-libsonnet/libgenerator/tfschema"
+)
+
+const (
+	olderThanFlagName = "older-than"
+)
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	flags := cachePruneCmd.Flags()
+
+	addCacheDirFlag(flags)
+	flags.Duration(
+		olderThanFlagName,
+		0,
+		strings.TrimSpace(`
+Only remove cache entries that were written longer ago than the given duration
+(e.g., 720h). When unset, all the entries are removed.
+`),
+	)
+}
+
+var (
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the provider schema cache",
+		Long:  `cache contains subcommands for managing the on-disk provider schema cache.`,
+	}
+
+	cachePruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Remove entries from the provider schema cache",
+		Long:  `prune removes the cached provider schemas from the schema cache directory.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cacheDir, err := parseCacheDir(cmd)
+			if err != nil {
+				return err
+			}
+
+			olderThan, err := cmd.Flags().GetDuration(olderThanFlagName)
+			if err != nil {
+				return err
+			}
+
+			logC, err := parseLoggerArgs()
+			if err != nil {
+				return err
+			}
+			logger := logging.GetSugaredLogger(logC)
+
+			cache, err := tfschema.NewSchemaCache(cacheDir)
+			if err != nil {
+				return err
+			}
+
+			removed, err := cache.Prune(olderThan)
+			if err != nil {
+				return err
+			}
+			logger.Infof("Removed %d entries from schema cache %s", removed, cache.Dir())
+			return nil
+		},
+	}
+)
@@ -1,19 +1,37 @@
 package cmdcfg
 
 import (
+	"context"
 	"fmt"
 	"net/url"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/hashicorp/go-version"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/tf-libsonnet/libgenerator/tfschema"
+	"go.uber.org/zap"
 )
 
 const (
-	providersFlagName = "provider"
-	tfVersionFlagName = "tfversion"
+	providersFlagName        = "provider"
+	tfVersionFlagName        = "tfversion"
+	backendFlagName          = "backend"
+	tofuPathFlagName         = "tofu-path"
+	tfPathFlagName           = "terraform-path"
+	jobsFlagName             = "jobs"
+	filesystemMirrorFlagName = "filesystem-mirror"
+	networkMirrorFlagName    = "network-mirror"
+	pluginCacheDirFlagName   = "plugin-cache-dir"
+	allowDirectFlagName      = "allow-direct-install"
+	cacheDirFlagName         = "cache-dir"
+	noCacheFlagName          = "no-cache"
+	timeoutFlagName          = "timeout"
+
+	tfBinaryEnvVar = "TF_BINARY"
 )
 
 func addProviderAndTFVersionFlags(flags *pflag.FlagSet) {
@@ -33,13 +51,148 @@ multiple providers.
 		strings.TrimSpace(`
 The version of Terraform to use when retrieving providers and their schema. If
 there is no compatible terraform version installed on the operator machine,
-libgenerator will download one from releases.hashicorp.com.
+libgenerator will download one from releases.hashicorp.com. Ignored when
+--terraform-path is set.
+`),
+	)
+	flags.String(
+		tfPathFlagName,
+		"",
+		strings.TrimSpace(`
+Path to a preinstalled Terraform binary to use when retrieving providers and
+their schema. When set, libgenerator will not download Terraform and will use
+the version of the given binary. Defaults to the value of the TF_BINARY
+environment variable.
+`),
+	)
+	flags.Int(
+		jobsFlagName,
+		0,
+		strings.TrimSpace(`
+When greater than 0, retrieve the schema for each provider in its own isolated
+workspace, running up to the given number of workspaces concurrently. In this
+mode, a provider that fails to initialize does not prevent retrieving the
+schemas of the other providers. When 0, all the providers are retrieved in a
+single workspace.
+`),
+	)
+	flags.String(
+		backendFlagName,
+		"terraform",
+		strings.TrimSpace(`
+The CLI tool to use when retrieving providers and their schema. Valid options:
+terraform, tofu. When tofu, provider sources without a hostname resolve against
+the OpenTofu registry (registry.opentofu.org).
+`),
+	)
+	flags.String(
+		tofuPathFlagName,
+		"",
+		strings.TrimSpace(`
+Path to the OpenTofu binary to use when retrieving providers and their schema.
+Implies --backend=tofu. When unset, the tofu binary is looked up on the PATH.
+`),
+	)
+}
+
+func addProviderInstallationFlags(flags *pflag.FlagSet) {
+	flags.StringSlice(
+		filesystemMirrorFlagName,
+		[]string{},
+		strings.TrimSpace(`
+Path to a local directory containing mirrored providers to install the
+providers from. Pass in multiple times to search multiple directories.
+`),
+	)
+	flags.StringSlice(
+		networkMirrorFlagName,
+		[]string{},
+		strings.TrimSpace(`
+Base URL of a provider network mirror to install the providers from. Pass in
+multiple times to use multiple mirrors.
+`),
+	)
+	flags.String(
+		pluginCacheDirFlagName,
+		"",
+		strings.TrimSpace(`
+Path to a directory to use as the shared provider plugin cache. When --jobs is
+set, each provider uses its own subdirectory, since the plugin cache can not be
+shared by concurrent runs.
+`),
+	)
+	flags.Bool(
+		allowDirectFlagName,
+		false,
+		strings.TrimSpace(`
+Allow installing providers directly from their origin registry when they are
+not available in any of the configured mirrors.
 `),
 	)
 }
 
+func addCacheDirFlag(flags *pflag.FlagSet) {
+	flags.String(
+		cacheDirFlagName,
+		"",
+		strings.TrimSpace(`
+Path to the directory where provider schemas are cached. Defaults to a
+libgenerator folder in the user cache directory (e.g., ~/.cache/libgenerator).
+`),
+	)
+}
+
+func addCacheFlags(flags *pflag.FlagSet) {
+	addCacheDirFlag(flags)
+	flags.Bool(
+		noCacheFlagName,
+		false,
+		strings.TrimSpace(`
+Disable the provider schema cache. When set, the schemas are always retrieved
+from the providers and the results are not stored in the cache.
+`),
+	)
+}
+
+func addTimeoutFlag(flags *pflag.FlagSet) {
+	flags.Duration(
+		timeoutFlagName,
+		0,
+		strings.TrimSpace(`
+Maximum amount of time to allow the command to run (e.g., 10m). When the
+timeout is reached, the command is aborted as if it was interrupted. When
+unset, the command runs until it completes.
+`),
+	)
+}
+
+// newCommandContext returns a context that is cancelled when the command receives an interrupt (Ctrl-C) or termination
+// signal, or when the --timeout is reached. The returned cancel function must be called to release the signal
+// handler.
+func newCommandContext(cmd *cobra.Command) (context.Context, context.CancelFunc, error) {
+	timeout, err := cmd.Flags().GetDuration(timeoutFlagName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if timeout < 0 {
+		return nil, nil, fmt.Errorf("--%s must not be negative", timeoutFlagName)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout == 0 {
+		return ctx, stop, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	cancelAll := func() {
+		cancel()
+		stop()
+	}
+	return ctx, cancelAll, nil
+}
+
 // parseProvidersInput parses the --provider arg list.
-func parseProvidersInput(cmd *cobra.Command) (tfschema.SchemaRequestList, error) {
+func parseProvidersInput(cmd *cobra.Command, backend tfschema.Backend) (tfschema.SchemaRequestList, error) {
 	providersInput, err := cmd.Flags().GetStringSlice(providersFlagName)
 	if err != nil {
 		return nil, err
@@ -61,7 +214,7 @@ func parseProvidersInput(cmd *cobra.Command) (tfschema.SchemaRequestList, error)
 
 		version := pinKV.Get("version")
 
-		req, err := tfschema.NewSchemaRequest(src, version)
+		req, err := tfschema.NewSchemaRequestForBackend(backend, src, version)
 		if err != nil {
 			return nil, err
 		}
@@ -71,6 +224,47 @@ func parseProvidersInput(cmd *cobra.Command) (tfschema.SchemaRequestList, error)
 	return out, nil
 }
 
+// parseGetSchemasOpts parses all the flags that configure how the provider schemas are retrieved.
+func parseGetSchemasOpts(cmd *cobra.Command) (*tfschema.GetSchemasOpts, error) {
+	backend, tofuPath, err := parseBackend(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	tfV, err := parseTerraformVersion(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	tfPath, err := parseTerraformPath(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	installCfg, err := parseProviderInstallation(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := cmd.Flags().GetInt(jobsFlagName)
+	if err != nil {
+		return nil, err
+	}
+	if jobs < 0 {
+		return nil, fmt.Errorf("--%s must not be negative", jobsFlagName)
+	}
+
+	opts := &tfschema.GetSchemasOpts{
+		Backend:              backend,
+		TerraformVersion:     tfV,
+		TerraformPath:        tfPath,
+		TofuPath:             tofuPath,
+		ProviderInstallation: installCfg,
+		Jobs:                 jobs,
+	}
+	return opts, nil
+}
+
 // parseTerraformVersion parses the --tfversion flag.
 func parseTerraformVersion(cmd *cobra.Command) (*version.Version, error) {
 	tfVersion, err := cmd.Flags().GetString(tfVersionFlagName)
@@ -80,3 +274,122 @@ func parseTerraformVersion(cmd *cobra.Command) (*version.Version, error) {
 
 	return version.NewVersion(tfVersion)
 }
+
+// parseTerraformPath parses the --terraform-path flag, falling back to the TF_BINARY environment variable.
+func parseTerraformPath(cmd *cobra.Command) (string, error) {
+	tfPath, err := cmd.Flags().GetString(tfPathFlagName)
+	if err != nil {
+		return "", err
+	}
+	if tfPath != "" {
+		return tfPath, nil
+	}
+	return os.Getenv(tfBinaryEnvVar), nil
+}
+
+// parseBackend parses the --backend and --tofu-path flags.
+func parseBackend(cmd *cobra.Command) (tfschema.Backend, string, error) {
+	backendName, err := cmd.Flags().GetString(backendFlagName)
+	if err != nil {
+		return tfschema.BackendTerraform, "", err
+	}
+
+	tofuPath, err := cmd.Flags().GetString(tofuPathFlagName)
+	if err != nil {
+		return tfschema.BackendTerraform, "", err
+	}
+	if tofuPath != "" {
+		return tfschema.BackendOpenTofu, tofuPath, nil
+	}
+
+	backend, err := tfschema.ParseBackend(backendName)
+	return backend, "", err
+}
+
+// parseProviderInstallation parses the provider installation flags to construct the provider installation config.
+// Returns nil if none of the flags are set, in which case the CLI configuration of the operator machine is used.
+func parseProviderInstallation(cmd *cobra.Command) (*tfschema.ProviderInstallationConfig, error) {
+	flags := cmd.Flags()
+
+	fsMirrors, err := flags.GetStringSlice(filesystemMirrorFlagName)
+	if err != nil {
+		return nil, err
+	}
+	netMirrors, err := flags.GetStringSlice(networkMirrorFlagName)
+	if err != nil {
+		return nil, err
+	}
+	pluginCacheDir, err := flags.GetString(pluginCacheDirFlagName)
+	if err != nil {
+		return nil, err
+	}
+	allowDirect, err := flags.GetBool(allowDirectFlagName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fsMirrors) == 0 && len(netMirrors) == 0 && pluginCacheDir == "" {
+		return nil, nil
+	}
+
+	cfg := &tfschema.ProviderInstallationConfig{
+		Direct:         allowDirect,
+		PluginCacheDir: pluginCacheDir,
+	}
+	for _, p := range fsMirrors {
+		cfg.FilesystemMirrors = append(cfg.FilesystemMirrors, tfschema.FilesystemMirror{Path: p})
+	}
+	for _, u := range netMirrors {
+		cfg.NetworkMirrors = append(cfg.NetworkMirrors, tfschema.NetworkMirror{URL: u})
+	}
+	return cfg, nil
+}
+
+// parseCacheDir parses the --cache-dir flag, falling back to the default cache directory when unset.
+func parseCacheDir(cmd *cobra.Command) (string, error) {
+	cacheDir, err := cmd.Flags().GetString(cacheDirFlagName)
+	if err != nil {
+		return "", err
+	}
+	if cacheDir != "" {
+		return cacheDir, nil
+	}
+	return tfschema.DefaultCacheDir()
+}
+
+// parseSchemaCache parses the --cache-dir and --no-cache flags to construct the schema cache. Returns nil if the cache
+// is disabled. This creates the cache directory, so this should only be called when the schemas are actually retrieved
+// from the providers.
+//
+// The cache is an optimization, so if the default cache directory can not be resolved or created, this logs a warning
+// and returns nil to continue without a cache. An error is only returned for a cache directory that is explicitly set
+// with --cache-dir.
+func parseSchemaCache(logger *zap.SugaredLogger, cmd *cobra.Command) (*tfschema.SchemaCache, error) {
+	noCache, err := cmd.Flags().GetBool(noCacheFlagName)
+	if err != nil {
+		return nil, err
+	}
+	if noCache {
+		return nil, nil
+	}
+
+	cacheDir, err := cmd.Flags().GetString(cacheDirFlagName)
+	if err != nil {
+		return nil, err
+	}
+	if cacheDir != "" {
+		return tfschema.NewSchemaCache(cacheDir)
+	}
+
+	cacheDir, err = tfschema.DefaultCacheDir()
+	if err != nil {
+		logger.Warnf("Could not resolve the default schema cache directory, continuing without a cache: %s", err)
+		return nil, nil
+	}
+	cache, err := tfschema.NewSchemaCache(cacheDir)
+	if err != nil {
+		logger.Warnf("Could not create the schema cache directory %s, continuing without a cache: %s", cacheDir, err)
+		return nil, nil
+	}
+	return cache, nil
+}
@@ -0,0 +1,83 @@
+package cmdcfg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// The test cases for parseSchemaCache are not run in parallel, as the default cache directory is derived from the
+// environment.
+func TestParseSchemaCache(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the default cache directory is only derived from XDG_CACHE_HOME on linux")
+	}
+	g := NewGomegaWithT(t)
+
+	tmpDir := t.TempDir()
+	notADir := filepath.Join(tmpDir, "file")
+	g.Expect(os.WriteFile(notADir, []byte{}, 0644)).To(Succeed())
+
+	testCases := []struct {
+		name          string
+		args          []string
+		xdgCacheHome  string
+		expectedDir   string
+		expectedWarns string
+		expectedErr   bool
+	}{
+		{"no_cache", []string{"--" + noCacheFlagName}, notADir, "", "", false},
+		{"default", nil, tmpDir, filepath.Join(tmpDir, "libgenerator"), "", false},
+		{
+			"explicit_dir",
+			[]string{"--" + cacheDirFlagName, filepath.Join(tmpDir, "explicit")},
+			notADir,
+			filepath.Join(tmpDir, "explicit"),
+			"",
+			false,
+		},
+		{"explicit_dir_can_not_be_created", []string{"--" + cacheDirFlagName, notADir}, tmpDir, "", "", true},
+		{"default_can_not_be_resolved", nil, "relative", "", "Could not resolve the default schema cache", false},
+		{"default_can_not_be_created", nil, notADir, "", "Could not create the schema cache directory", false},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			t.Setenv("XDG_CACHE_HOME", tc.xdgCacheHome)
+
+			cmd := &cobra.Command{}
+			addCacheFlags(cmd.Flags())
+			g.Expect(cmd.Flags().Parse(tc.args)).To(Succeed())
+
+			core, logs := observer.New(zapcore.WarnLevel)
+			cache, err := parseSchemaCache(zap.New(core).Sugar(), cmd)
+			if tc.expectedErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			if tc.expectedDir == "" {
+				g.Expect(cache).To(BeNil())
+			} else {
+				g.Expect(cache).NotTo(BeNil())
+				g.Expect(cache.Dir()).To(Equal(tc.expectedDir))
+				g.Expect(tc.expectedDir).To(BeADirectory())
+			}
+
+			if tc.expectedWarns == "" {
+				g.Expect(logs.Len()).To(Equal(0))
+			} else {
+				g.Expect(logs.FilterMessageSnippet(tc.expectedWarns).Len()).To(Equal(1))
+			}
+		})
+	}
+}
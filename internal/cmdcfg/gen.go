@@ -1,21 +1,34 @@
 package cmdcfg
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/spf13/cobra"
 	"github.com/tf-libsonnet/libgenerator/internal/gen"
 	"github.com/tf-libsonnet/libgenerator/internal/logging"
 	"github.com/tf-libsonnet/libgenerator/tfschema"
+	"go.uber.org/zap"
 )
 
 const (
-	outDirFlagName = "out"
-	configFlagName = "config"
+	outDirFlagName          = "out"
+	configFlagName          = "config"
+	schemaFileFlagName      = "schema-file"
+	lockedFlagName          = "locked"
+	assertionsFlagName      = "with-assertions"
+	warnDeprecatedFlagName  = "warn-deprecated"
+	skipDeprecatedFlagName  = "skip-deprecated"
+	sensitiveAttrsFlagName  = "with-sensitive-attrs"
+	withDocsFlagName        = "with-docs"
+	skipDataSourcesFlagName = "skip-data-sources"
+	groupBySegmentFlagName  = "group-by-segment"
+	renderJobsFlagName      = "render-jobs"
 )
 
 func init() {
@@ -23,6 +36,9 @@ func init() {
 	flags := genCmd.Flags()
 
 	addProviderAndTFVersionFlags(flags)
+	addProviderInstallationFlags(flags)
+	addCacheFlags(flags)
+	addTimeoutFlag(flags)
 	flags.String(
 		outDirFlagName,
 		"./out",
@@ -37,7 +53,96 @@ provider name.
 		"",
 		strings.TrimSpace("Path to a config file containing the list of libraries to render."),
 	)
-
+	flags.StringSlice(
+		schemaFileFlagName,
+		[]string{},
+		strings.TrimSpace(`
+Path to a file containing provider schemas, as output by libgenerator getschema
+or terraform providers schema -json. When set, the schemas are loaded from the
+files instead of running Terraform. Pass in multiple times for loading schemas
+from multiple files.
+`),
+	)
+	flags.Bool(
+		lockedFlagName,
+		false,
+		strings.TrimSpace(`
+Retrieve the exact provider versions recorded in the libgenerator.lock.json
+file in the output directory, instead of resolving the version constraints in
+the config. Fails if any of the providers are missing from the lock file.
+`),
+	)
+	flags.Bool(
+		assertionsFlagName,
+		false,
+		strings.TrimSpace(`
+Add assertions to the generated constructors that check the types of the
+attributes and blocks when the Jsonnet code is evaluated. This catches type
+errors during evaluation instead of at terraform plan time, at the cost of
+slightly slower evaluation.
+`),
+	)
+	flags.Bool(
+		warnDeprecatedFlagName,
+		false,
+		strings.TrimSpace(`
+Make the generated functions emit a warning with std.trace when an attribute
+or block that is deprecated by the provider is set.
+`),
+	)
+	flags.Bool(
+		skipDeprecatedFlagName,
+		false,
+		strings.TrimSpace(`
+Leave out the attributes and blocks that are deprecated by the provider from
+the generated libraries entirely.
+`),
+	)
+	flags.Bool(
+		sensitiveAttrsFlagName,
+		false,
+		strings.TrimSpace(`
+Add a sensitiveAttrs function to each generated provider, resource, and data
+source that lists the attributes that are marked as sensitive or write-only by
+the provider. This can be used to check that secrets are not hard-coded in the
+Jsonnet code.
+`),
+	)
+	flags.Bool(
+		withDocsFlagName,
+		false,
+		strings.TrimSpace(`
+Render Markdown reference docs for the provider, resources, and data sources
+into the docs folder of each generated library.
+`),
+	)
+	flags.Bool(
+		skipDataSourcesFlagName,
+		false,
+		strings.TrimSpace(`
+Omit the data sources from all the generated libraries. Data sources can also
+be skipped for individual providers with skip_data_sources in the config file.
+`),
+	)
+	flags.Int(
+		groupBySegmentFlagName,
+		0,
+		strings.TrimSpace(`
+Group the resources and data sources into sub libraries by the segment of the
+name at the given position, starting from 1 (e.g., 1 to group aws_s3_bucket into
+s3), so that only the groups that are used are imported. Set to 0 to disable
+grouping. This can be overridden for individual providers with group_by_segment
+in the config file.
+`),
+	)
+	flags.Int(
+		renderJobsFlagName,
+		0,
+		strings.TrimSpace(`
+The maximum number of resource and data source files to render concurrently.
+When 0, this defaults to the number of CPUs that are available.
+`),
+	)
 }
 
 var (
@@ -47,9 +152,12 @@ var (
 		Long: `gen generates libsonnet libraries for any given Terraform provider.
 
 This command will:
-- Retrieve the schema for resources and data sources from the provider.
+- Retrieve the schema for resources and data sources from the provider, or load it
+  from the files passed in with --schema-file.
 - Generate corresponding libsonnet files from the schema.
 - Write the libsonnet files to a subfolder named after the libraryName.
+- Record the resolved provider versions in libgenerator.lock.json in the output
+  directory.
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			configFile, err := cmd.Flags().GetString(configFlagName)
@@ -57,17 +165,22 @@ This command will:
 				return err
 			}
 
+			opts, err := parseGetSchemasOpts(cmd)
+			if err != nil {
+				return err
+			}
+
 			var genCfg *genConfig
 			if configFile == "" {
-				genCfg, err = extractConfigFromProvidersInput(cmd)
+				genCfg, err = extractConfigFromProvidersInput(cmd, opts.Backend)
 			} else {
-				genCfg, err = parseConfigFile(configFile)
+				genCfg, err = parseConfigFile(configFile, opts.Backend)
 			}
 			if err != nil {
 				return err
 			}
 
-			tfV, err := parseTerraformVersion(cmd)
+			schemaFiles, err := cmd.Flags().GetStringSlice(schemaFileFlagName)
 			if err != nil {
 				return err
 			}
@@ -77,41 +190,187 @@ This command will:
 				return err
 			}
 
+			locked, err := cmd.Flags().GetBool(lockedFlagName)
+			if err != nil {
+				return err
+			}
+
+			withAssertions, err := cmd.Flags().GetBool(assertionsFlagName)
+			if err != nil {
+				return err
+			}
+			warnDeprecated, err := cmd.Flags().GetBool(warnDeprecatedFlagName)
+			if err != nil {
+				return err
+			}
+			skipDeprecated, err := cmd.Flags().GetBool(skipDeprecatedFlagName)
+			if err != nil {
+				return err
+			}
+			withSensitiveAttrs, err := cmd.Flags().GetBool(sensitiveAttrsFlagName)
+			if err != nil {
+				return err
+			}
+			withDocs, err := cmd.Flags().GetBool(withDocsFlagName)
+			if err != nil {
+				return err
+			}
+			skipDataSources, err := cmd.Flags().GetBool(skipDataSourcesFlagName)
+			if err != nil {
+				return err
+			}
+			groupBySegment, err := cmd.Flags().GetInt(groupBySegmentFlagName)
+			if err != nil {
+				return err
+			}
+			if groupBySegment < 0 {
+				return fmt.Errorf("--%s must not be negative", groupBySegmentFlagName)
+			}
+			renderJobs, err := cmd.Flags().GetInt(renderJobsFlagName)
+			if err != nil {
+				return err
+			}
+			if renderJobs < 0 {
+				return fmt.Errorf("--%s must not be negative", renderJobsFlagName)
+			}
+			if locked && len(schemaFiles) > 0 {
+				return fmt.Errorf("--%s can not be used with --%s", lockedFlagName, schemaFileFlagName)
+			}
+
+			prevLock, err := readLockFile(outDir)
+			if err != nil {
+				return err
+			}
+			if locked {
+				if err := applyLockFile(genCfg, prevLock); err != nil {
+					return err
+				}
+			}
+
 			logC, err := parseLoggerArgs()
 			if err != nil {
 				return err
 			}
 			logger := logging.GetSugaredLogger(logC)
 
-			logger.Info("Retrieving schemas for providers")
-			ctx := context.Background()
-			schema, err := tfschema.GetSchemas(logger, ctx, tfV, genCfg.requests)
+			ctx, cancel, err := newCommandContext(cmd)
 			if err != nil {
 				return err
 			}
+			defer cancel()
+
+			var schemas map[string]*tfjson.ProviderSchema
+			var locks tfschema.ProviderLocks
+			tfVersion := ""
+			if len(schemaFiles) > 0 {
+				logger.Info("Loading schemas for providers from schema files")
+				schemas, err = loadSchemasByRequest(logger, schemaFiles, genCfg.requests)
+			} else {
+				opts.Cache, err = parseSchemaCache(logger, cmd)
+				if err != nil {
+					return err
+				}
+
+				cliV, vErr := tfschema.CLIVersion(logger, ctx, *opts)
+				if vErr != nil {
+					return vErr
+				}
+				tfVersion = cliV.String()
+
+				logger.Info("Retrieving schemas for providers")
+				schemas, locks, err = tfschema.GetSchemasByRequest(logger, ctx, genCfg.requests, *opts)
+			}
+
+			// When retrieving the schemas in isolated mode, render the libraries for the providers that succeeded and
+			// report the failures at the end.
+			var schemaErrs *tfschema.SchemaErrors
+			if errors.As(err, &schemaErrs) {
+				for k, pErr := range schemaErrs.Errors {
+					logger.Errorf("Skipping %s due to error retrieving schema: %s", k, pErr)
+				}
+			} else if err != nil {
+				return err
+			}
 
 			for _, entry := range genCfg.entries {
-				k := entry.Provider.schemaRequest.Src
-				pName := entry.Provider.schemaRequest.Name
+				req := entry.Provider.schemaRequest
+				k := req.Key()
+				pName := req.Name
+
+				if schemaErrs != nil {
+					if _, failed := schemaErrs.Errors[k]; failed {
+						continue
+					}
+				}
+				providerSchema, hasSchema := schemas[k]
+				if !hasSchema {
+					return fmt.Errorf("could not find schema for provider %s", k)
+				}
+				providerVersion := ""
+				if lock, hasLock := locks[k]; hasLock {
+					logger.Infof("Resolved %s to version %s", k, lock.Version)
+					providerVersion = lock.Version
+				}
 
 				libRoot := filepath.Join(outDir, entry.Repo, entry.Subdir)
 				if err := os.MkdirAll(libRoot, 0755); err != nil {
 					return err
 				}
 
+				entryGroupBySegment := groupBySegment
+				if entry.GroupBySegment != nil {
+					entryGroupBySegment = *entry.GroupBySegment
+				}
+				if entryGroupBySegment < 0 {
+					return fmt.Errorf("group_by_segment for %s must not be negative", k)
+				}
+
 				logger.Infof("Rendering %s library to %s", k, libRoot)
-				providerSchema := schema.Schemas[k]
-				opts := gen.RenderLibraryOpts{
+				renderOpts := gen.RenderLibraryOpts{
 					ProviderName:   pName,
 					Schema:         providerSchema,
 					ResourcePrefix: entry.ResourcePrefix,
+
+					ProviderSrc:      req.Src,
+					ProviderVersion:  providerVersion,
+					TerraformVersion: tfVersion,
+					GeneratorVersion: Version,
+					ProviderDocURL:   entry.DocsURL,
+
+					WithAssertions: withAssertions,
+					WarnDeprecated: warnDeprecated,
+					SkipDeprecated: skipDeprecated,
+
+					WithSensitiveAttrs: withSensitiveAttrs,
+					WithDocs:           withDocs,
+
+					IncludeResources:   entry.Resources.include(),
+					ExcludeResources:   entry.Resources.exclude(),
+					IncludeDataSources: entry.DataSources.include(),
+					ExcludeDataSources: entry.DataSources.exclude(),
+					SkipDataSources:    skipDataSources || entry.SkipDataSources,
+					GroupBySegment:     entryGroupBySegment,
+					RenderJobs:         renderJobs,
 				}
-				renderErr := gen.RenderLibrary(logger, libRoot, opts)
+				renderErr := gen.RenderLibrary(logger, ctx, libRoot, renderOpts)
 				if renderErr != nil {
 					return renderErr
 				}
 			}
 
+			// The schema files do not record the provider versions, so the lock file can only be updated when the
+			// schemas are retrieved from the providers.
+			if len(schemaFiles) == 0 {
+				lf := updateLockFile(genCfg, prevLock, locks)
+				logger.Infof("Writing resolved provider versions to %s", filepath.Join(outDir, lockFileName))
+				if err := writeLockFile(outDir, lf); err != nil {
+					return err
+				}
+			}
+
+			if schemaErrs != nil {
+				return schemaErrs
+			}
 			return nil
 		},
 	}
@@ -127,6 +386,43 @@ type configEntry struct {
 	Subdir         string          `json:"subdir"`
 	Provider       *providerConfig `json:"provider"`
 	ResourcePrefix string          `json:"resource_prefix,omitempty"`
+
+	// DocsURL overrides the URL to the provider docs that is linked from the generated docs. By default, this is derived
+	// from the provider source and resolved version.
+	DocsURL string `json:"docs_url,omitempty"`
+
+	// Resources and DataSources filter the resources and data sources that are rendered into the library.
+	Resources   *objectFilter `json:"resources,omitempty"`
+	DataSources *objectFilter `json:"data_sources,omitempty"`
+
+	// SkipDataSources omits all the data sources from the library.
+	SkipDataSources bool `json:"skip_data_sources,omitempty"`
+
+	// GroupBySegment groups the resources and data sources into sub libraries by the name segment at the given position
+	// (e.g., 1 to group aws_s3_bucket into s3). This overrides the --group-by-segment flag when set, including when set
+	// to 0 to disable grouping for the library.
+	GroupBySegment *int `json:"group_by_segment,omitempty"`
+}
+
+// objectFilter is a list of include and exclude patterns for filtering resources or data sources by type name (e.g.,
+// aws_s3_bucket). Patterns wrapped in slashes are regular expressions, and all other patterns are globs.
+type objectFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+func (f *objectFilter) include() []string {
+	if f == nil {
+		return nil
+	}
+	return f.Include
+}
+
+func (f *objectFilter) exclude() []string {
+	if f == nil {
+		return nil
+	}
+	return f.Exclude
 }
 
 type providerConfig struct {
@@ -136,7 +432,7 @@ type providerConfig struct {
 	schemaRequest *tfschema.SchemaRequest
 }
 
-func parseConfigFile(config string) (*genConfig, error) {
+func parseConfigFile(config string, backend tfschema.Backend) (*genConfig, error) {
 	cfgContents, err := os.ReadFile(config)
 	if err != nil {
 		return nil, err
@@ -149,7 +445,7 @@ func parseConfigFile(config string) (*genConfig, error) {
 
 	requests := tfschema.SchemaRequestList{}
 	for _, c := range entries {
-		req, err := tfschema.NewSchemaRequest(c.Provider.Src, c.Provider.Version)
+		req, err := tfschema.NewSchemaRequestForBackend(backend, c.Provider.Src, c.Provider.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -163,8 +459,8 @@ func parseConfigFile(config string) (*genConfig, error) {
 	return cfg, nil
 }
 
-func extractConfigFromProvidersInput(cmd *cobra.Command) (*genConfig, error) {
-	requests, err := parseProvidersInput(cmd)
+func extractConfigFromProvidersInput(cmd *cobra.Command, backend tfschema.Backend) (*genConfig, error) {
+	requests, err := parseProvidersInput(cmd, backend)
 	if err != nil {
 		return nil, err
 	}
@@ -187,3 +483,25 @@ func extractConfigFromProvidersInput(cmd *cobra.Command) (*genConfig, error) {
 	}
 	return cfg, nil
 }
+
+// loadSchemasByRequest loads the provider schemas from the given schema files, and routes them to the requests. Since
+// the schema files only contain one schema per provider source, all the requests for the same provider share the same
+// schema.
+func loadSchemasByRequest(
+	logger *zap.SugaredLogger,
+	schemaFiles []string,
+	requests tfschema.SchemaRequestList,
+) (map[string]*tfjson.ProviderSchema, error) {
+	loaded, err := tfschema.LoadSchemas(logger, schemaFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]*tfjson.ProviderSchema{}
+	for _, req := range requests {
+		if schema, hasSchema := loaded.Schemas[req.Src]; hasSchema {
+			out[req.Key()] = schema
+		}
+	}
+	return out, nil
+}
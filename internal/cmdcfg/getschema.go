@@ -1,8 +1,8 @@
 package cmdcfg
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -15,6 +15,9 @@ func init() {
 	flags := getschemaCmd.Flags()
 
 	addProviderAndTFVersionFlags(flags)
+	addProviderInstallationFlags(flags)
+	addCacheFlags(flags)
+	addTimeoutFlag(flags)
 }
 
 var (
@@ -23,12 +26,12 @@ var (
 		Short: "Get the schema from Terraform providers",
 		Long:  `getschema gets the resource and data source schemas from any given Terraform provider.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			req, err := parseProvidersInput(cmd)
+			opts, err := parseGetSchemasOpts(cmd)
 			if err != nil {
 				return err
 			}
 
-			tfV, err := parseTerraformVersion(cmd)
+			req, err := parseProvidersInput(cmd, opts.Backend)
 			if err != nil {
 				return err
 			}
@@ -39,18 +42,38 @@ var (
 			}
 			logger := logging.GetSugaredLogger(logC)
 
-			ctx := context.Background()
-			schema, err := tfschema.GetSchemas(logger, ctx, tfV, req)
+			opts.Cache, err = parseSchemaCache(logger, cmd)
 			if err != nil {
 				return err
 			}
 
+			ctx, cancel, err := newCommandContext(cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			schema, locks, err := tfschema.GetSchemas(logger, ctx, req, *opts)
+
+			// When retrieving the schemas in isolated mode, output the schemas for the providers that succeeded before
+			// reporting the failures.
+			var schemaErrs *tfschema.SchemaErrors
+			if err != nil && !errors.As(err, &schemaErrs) {
+				return err
+			}
+			for src, lock := range locks {
+				logger.Infof("Resolved %s to version %s", src, lock.Version)
+			}
+
 			out, err := json.Marshal(schema)
 			if err != nil {
 				return err
 			}
 			fmt.Println(string(out))
 
+			if schemaErrs != nil {
+				return schemaErrs
+			}
 			return nil
 		},
 	}
@@ -0,0 +1,124 @@
+package cmdcfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/tf-libsonnet/libgenerator/tfschema"
+)
+
+const (
+	lockFileName = "libgenerator.lock.json"
+)
+
+// lockFile is the representation of the libgenerator.lock.json file that records the exact provider versions that were
+// used to generate the libraries in the output directory.
+type lockFile struct {
+	// Providers maps the provider source and the version constraint in the config (joined with an @) to the lock
+	// information of the provider version that was resolved for the constraint.
+	Providers map[string]*tfschema.ProviderLock `json:"providers"`
+}
+
+// lockKey returns the key of the given config entry in the lock file. This is always based on the version constraint
+// in the config so that the entry can be looked up in --locked mode.
+func lockKey(entry configEntry) string {
+	req := tfschema.SchemaRequest{
+		Src:     entry.Provider.schemaRequest.Src,
+		Version: entry.Provider.Version,
+	}
+	return req.Key()
+}
+
+// readLockFile reads the lock file in the given output directory. Returns nil if the lock file does not exist.
+func readLockFile(outDir string) (*lockFile, error) {
+	data, err := os.ReadFile(filepath.Join(outDir, lockFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var lf lockFile
+	if err := json.Unmarshal(data, &lf); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", lockFileName, err)
+	}
+	if lf.Providers == nil {
+		lf.Providers = map[string]*tfschema.ProviderLock{}
+	}
+	return &lf, nil
+}
+
+// writeLockFile writes the lock file to the given output directory. The lock file is written to a temporary file first
+// and moved into place so that an interrupted run never leaves behind a partial lock file.
+func writeLockFile(outDir string, lf *lockFile) error {
+	// Disable HTML escaping so that the version constraints in the keys (e.g., ~>3.0) remain readable.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(lf); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	tmpF, err := os.CreateTemp(outDir, lockFileName+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpF.Name())
+
+	if _, err := tmpF.Write(buf.Bytes()); err != nil {
+		tmpF.Close()
+		return err
+	}
+	if err := tmpF.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpF.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpF.Name(), filepath.Join(outDir, lockFileName))
+}
+
+// applyLockFile pins the schema request of each config entry to the exact provider version recorded in the lock file.
+// Returns an error if any of the entries are missing from the lock file.
+func applyLockFile(genCfg *genConfig, lf *lockFile) error {
+	if lf == nil {
+		return fmt.Errorf("--%s was set, but could not find %s in the output directory", lockedFlagName, lockFileName)
+	}
+
+	for _, entry := range genCfg.entries {
+		k := lockKey(entry)
+		lock, hasLock := lf.Providers[k]
+		if !hasLock || lock.Version == "" {
+			return fmt.Errorf("provider %s is missing from %s. Rerun without --%s to update it", k, lockFileName, lockedFlagName)
+		}
+		entry.Provider.schemaRequest.Version = "=" + lock.Version
+	}
+	return nil
+}
+
+// updateLockFile constructs the new lock file from the provider locks that were resolved during the current run.
+// Entries that failed to resolve in the current run carry over the lock information from the previous lock file, if
+// any. The locks map is keyed by the request Key.
+func updateLockFile(genCfg *genConfig, prev *lockFile, locks tfschema.ProviderLocks) *lockFile {
+	out := &lockFile{Providers: map[string]*tfschema.ProviderLock{}}
+	for _, entry := range genCfg.entries {
+		k := lockKey(entry)
+		if lock, hasLock := locks[entry.Provider.schemaRequest.Key()]; hasLock {
+			out.Providers[k] = lock
+		} else if prev != nil {
+			if lock, hasLock := prev.Providers[k]; hasLock {
+				out.Providers[k] = lock
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,302 @@
+package cmdcfg
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/tf-libsonnet/libgenerator/tfschema"
+)
+
+const (
+	testNullSrc = "registry.terraform.io/hashicorp/null"
+	testAWSSrc  = "registry.terraform.io/hashicorp/aws"
+)
+
+// newTestGenConfig returns a genConfig with an entry for each of the given provider source and version pairs.
+func newTestGenConfig(g *WithT, providers ...[2]string) *genConfig {
+	cfg := &genConfig{}
+	for _, p := range providers {
+		req, err := tfschema.NewSchemaRequestForBackend(tfschema.BackendTerraform, p[0], p[1])
+		g.Expect(err).NotTo(HaveOccurred())
+
+		cfg.requests = append(cfg.requests, req)
+		cfg.entries = append(cfg.entries, configEntry{
+			Repo: req.Name,
+			Provider: &providerConfig{
+				Src:           p[0],
+				Version:       p[1],
+				schemaRequest: req,
+			},
+		})
+	}
+	return cfg
+}
+
+func TestLockKey(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		src      string
+		version  string
+		locked   bool
+		expected string
+	}{
+		{"constraint", "hashicorp/null", "~>3.0", false, testNullSrc + "@~>3.0"},
+		{"no_version", "hashicorp/null", "", false, testNullSrc},
+		{"short_src", "null", "3.2.1", false, testNullSrc + "@3.2.1"},
+
+		// applyLockFile rewrites the request version to the exact locked version, but the lock key should still use the
+		// original constraint so that the entry is found again on the next run.
+		{"locked_constraint", "hashicorp/null", "~>3.0", true, testNullSrc + "@~>3.0"},
+		{"locked_no_version", "hashicorp/null", "", true, testNullSrc},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			cfg := newTestGenConfig(g, [2]string{tc.src, tc.version})
+			if tc.locked {
+				lf := &lockFile{Providers: map[string]*tfschema.ProviderLock{
+					tc.expected: {Src: testNullSrc, Version: "3.2.1"},
+				}}
+				g.Expect(applyLockFile(cfg, lf)).To(Succeed())
+				g.Expect(cfg.entries[0].Provider.schemaRequest.Version).To(Equal("=3.2.1"))
+			}
+			g.Expect(lockKey(cfg.entries[0])).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestApplyLockFile(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		lf               *lockFile
+		expectedVersions []string
+		expectedErr      string
+	}{
+		{
+			"all_locked",
+			&lockFile{Providers: map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": {Src: testNullSrc, Version: "3.2.1"},
+				testAWSSrc + "@~>5.0":  {Src: testAWSSrc, Version: "5.31.0"},
+			}},
+			[]string{"=3.2.1", "=5.31.0"},
+			"",
+		},
+		{
+			"missing_entry",
+			&lockFile{Providers: map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": {Src: testNullSrc, Version: "3.2.1"},
+			}},
+			nil,
+			"provider " + testAWSSrc + "@~>5.0 is missing from " + lockFileName + ". Rerun without --locked to update it",
+		},
+		{
+			"entry_for_other_constraint",
+			&lockFile{Providers: map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": {Src: testNullSrc, Version: "3.2.1"},
+				testAWSSrc + "@~>4.0":  {Src: testAWSSrc, Version: "4.67.0"},
+			}},
+			nil,
+			"provider " + testAWSSrc + "@~>5.0 is missing from " + lockFileName + ". Rerun without --locked to update it",
+		},
+		{
+			"entry_without_version",
+			&lockFile{Providers: map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": {Src: testNullSrc, Version: "3.2.1"},
+				testAWSSrc + "@~>5.0":  {Src: testAWSSrc},
+			}},
+			nil,
+			"provider " + testAWSSrc + "@~>5.0 is missing from " + lockFileName + ". Rerun without --locked to update it",
+		},
+		{
+			"missing_lock_file",
+			nil,
+			nil,
+			"--locked was set, but could not find " + lockFileName + " in the output directory",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			cfg := newTestGenConfig(g, [2]string{"hashicorp/null", "~>3.0"}, [2]string{"hashicorp/aws", "~>5.0"})
+			err := applyLockFile(cfg, tc.lf)
+			if tc.expectedErr != "" {
+				g.Expect(err).To(MatchError(tc.expectedErr))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+
+			versions := []string{}
+			for _, req := range cfg.requests {
+				versions = append(versions, req.Version)
+			}
+			g.Expect(versions).To(Equal(tc.expectedVersions))
+		})
+	}
+}
+
+func TestUpdateLockFile(t *testing.T) {
+	t.Parallel()
+
+	nullLock := &tfschema.ProviderLock{Src: testNullSrc, Version: "3.2.1", Constraints: "~> 3.0"}
+	newNullLock := &tfschema.ProviderLock{Src: testNullSrc, Version: "3.2.2", Constraints: "~> 3.0"}
+	awsLock := &tfschema.ProviderLock{Src: testAWSSrc, Version: "5.31.0", Constraints: "~> 5.0"}
+
+	testCases := []struct {
+		name     string
+		prev     *lockFile
+		locked   bool
+		locks    tfschema.ProviderLocks
+		expected map[string]*tfschema.ProviderLock
+	}{
+		{
+			"all_resolved",
+			nil,
+			false,
+			tfschema.ProviderLocks{
+				testNullSrc + "@~>3.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			},
+			map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			},
+		},
+		{
+			"resolved_replaces_previous",
+			&lockFile{Providers: map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			}},
+			false,
+			tfschema.ProviderLocks{
+				testNullSrc + "@~>3.0": newNullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			},
+			map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": newNullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			},
+		},
+		{
+			// In --jobs mode, the providers that fail to resolve are skipped and do not have a lock, so the previous lock
+			// entry should be carried over.
+			"failed_provider_carries_over_previous",
+			&lockFile{Providers: map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			}},
+			false,
+			tfschema.ProviderLocks{
+				testNullSrc + "@~>3.0": newNullLock,
+			},
+			map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": newNullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			},
+		},
+		{
+			"failed_provider_without_previous",
+			&lockFile{Providers: map[string]*tfschema.ProviderLock{}},
+			false,
+			tfschema.ProviderLocks{
+				testNullSrc + "@~>3.0": nullLock,
+			},
+			map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": nullLock,
+			},
+		},
+		{
+			"drops_entries_not_in_config",
+			&lockFile{Providers: map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>2.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			}},
+			false,
+			tfschema.ProviderLocks{
+				testNullSrc + "@~>3.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			},
+			map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			},
+		},
+		{
+			// In --locked mode, the providers are requested with the exact locked version, so the resolved locks are keyed
+			// by the pinned version and must be mapped back to the original constraint.
+			"locked",
+			&lockFile{Providers: map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			}},
+			true,
+			tfschema.ProviderLocks{
+				testNullSrc + "@=3.2.1": nullLock,
+				testAWSSrc + "@=5.31.0": awsLock,
+			},
+			map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			},
+		},
+		{
+			"locked_failed_provider_carries_over_previous",
+			&lockFile{Providers: map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			}},
+			true,
+			tfschema.ProviderLocks{
+				testNullSrc + "@=3.2.1": nullLock,
+			},
+			map[string]*tfschema.ProviderLock{
+				testNullSrc + "@~>3.0": nullLock,
+				testAWSSrc + "@~>5.0":  awsLock,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			cfg := newTestGenConfig(g, [2]string{"hashicorp/null", "~>3.0"}, [2]string{"hashicorp/aws", "~>5.0"})
+			if tc.locked {
+				g.Expect(applyLockFile(cfg, tc.prev)).To(Succeed())
+			}
+			lf := updateLockFile(cfg, tc.prev, tc.locks)
+			g.Expect(lf.Providers).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestLockFileRoundTrip(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	outDir := t.TempDir()
+	lf, err := readLockFile(outDir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(lf).To(BeNil())
+
+	expected := &lockFile{Providers: map[string]*tfschema.ProviderLock{
+		testNullSrc + "@~>3.0": {Src: testNullSrc, Version: "3.2.1", Constraints: "~> 3.0", Hashes: []string{"h1:abc="}},
+	}}
+	g.Expect(writeLockFile(outDir, expected)).To(Succeed())
+
+	lf, err = readLockFile(outDir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(lf).To(Equal(expected))
+}
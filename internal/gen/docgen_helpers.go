@@ -1,10 +1,17 @@
 package gen
 
 import (
+	"fmt"
+	"strings"
+
 	_ "embed"
 
 	tfjson "github.com/hashicorp/terraform-json"
+	tfaddr "github.com/hashicorp/terraform-registry-address"
+	j "github.com/jsonnet-libs/k8s/pkg/builder"
 	"github.com/zclconf/go-cty/cty"
+
+	"github.com/tf-libsonnet/libgenerator/tfschema"
 )
 
 func getAttrType(attr *tfjson.SchemaAttribute) string {
@@ -48,3 +55,127 @@ func getBlockType(nestingMode tfjson.SchemaNestingMode) string {
 	}
 	return "obj"
 }
+
+// docArgType returns the docsonnet type (a field of d.T) for the given type string as returned by getAttrType and
+// getBlockType.
+func docArgType(typ string) string {
+	switch typ {
+	case "string":
+		return "string"
+	case "number":
+		return "number"
+	case "bool":
+		return "boolean"
+	case "list", "list[obj]":
+		return "array"
+	case "obj", "map[str, obj]":
+		return "object"
+	}
+	return "any"
+}
+
+// docArg returns the docsonnet d.arg entry for a function parameter with the given name and type string. The default
+// is a raw jsonnet expression, and is omitted when empty (for required parameters).
+func docArg(name, typ, defaultExpr string) j.Type {
+	args := []j.Type{
+		j.String("name", name),
+		j.Ref("type", "d.T."+docArgType(typ)),
+	}
+	if defaultExpr != "" {
+		args = append(args, j.Ref("default", defaultExpr))
+	}
+	return j.Call("", "d.arg", args)
+}
+
+// constructorDocArgs returns the docsonnet d.arg entries for the parameters of a constructor for the given schema, in
+// the same order as the parameters returned by constructorParamList. Optional parameters default to null.
+func constructorDocArgs(schema *tfjson.SchemaBlock) []j.Type {
+	types := map[string]string{}
+	required := map[string]bool{}
+	for param, cfg := range getInputAttributes(schema) {
+		types[param] = getAttrType(cfg.attr)
+		required[param] = cfg.attr.Required
+	}
+	for param, cfg := range getNestedBlocks(schema) {
+		types[param] = getBlockType(cfg.block.NestingMode)
+	}
+
+	args := []j.Type{}
+	for _, p := range constructorParamList(schema).params {
+		defaultExpr := "null"
+		if required[p.Name()] {
+			defaultExpr = ""
+		}
+		args = append(args, docArg(p.Name(), types[p.Name()], defaultExpr))
+	}
+	return args
+}
+
+// withFnDocArgs returns the docsonnet d.arg entries for the parameters of a with function, matching withFnArgs. The
+// label arg is omitted when labelArgName is empty.
+func withFnDocArgs(labelArgName, typ string, flavor withFnFlavor) []j.Type {
+	args := []j.Type{}
+	if labelArgName != "" {
+		args = append(args, docArg(labelArgName, "string", ""))
+	}
+	if flavor == IsMixinAt {
+		// The value for patching a single element of a list is an object, not the list type.
+		args = append(args, docArg(indexArgName, "number", ""), docArg(valueArgName, "obj", ""))
+		return args
+	}
+	return append(args, docArg(valueArgName, typ, ""))
+}
+
+// providerDocURL returns the URL to the registry docs of the provider with the given source and exact version. When the
+// version is empty, this links to the latest version of the docs. Providers from the OpenTofu registry link to the
+// Terraform registry docs, since the OpenTofu registry mirrors the providers under the same namespace and type. This
+// returns an empty string for providers that are hosted on any other registry, since there is no known docs URL
+// convention for other registries.
+func providerDocURL(src, version string) (string, error) {
+	if src == "" {
+		return "", nil
+	}
+	pAddr, err := tfaddr.ParseProviderSource(src)
+	if err != nil {
+		return "", err
+	}
+	switch pAddr.Hostname.String() {
+	case tfaddr.DefaultProviderRegistryHost.String():
+	case tfschema.BackendOpenTofu.RegistryHost():
+		pAddr.Hostname = tfaddr.DefaultProviderRegistryHost
+	default:
+		return "", nil
+	}
+
+	if version == "" {
+		version = "latest"
+	}
+	return fmt.Sprintf(
+		"https://%s/providers/%s/%s/%s/docs",
+		pAddr.Hostname, pAddr.Namespace, pAddr.Type, strings.TrimPrefix(version, "v"),
+	), nil
+}
+
+// objectDocURL returns the URL to the registry docs page of the given resource or data source, given the provider docs
+// URL (see providerDocURL). objectName is the name of the resource or data source without the provider prefix. This
+// returns an empty string if the provider docs URL is unknown.
+func objectDocURL(providerDocURL, objectName string, resrcOrDataSrc resourceOrDataSource) string {
+	if providerDocURL == "" {
+		return ""
+	}
+
+	switch resrcOrDataSrc {
+	case IsResource:
+		return fmt.Sprintf("%s/resources/%s", strings.TrimSuffix(providerDocURL, "/"), objectName)
+	case IsDataSource:
+		return fmt.Sprintf("%s/data-sources/%s", strings.TrimSuffix(providerDocURL, "/"), objectName)
+	}
+	return providerDocURL
+}
+
+// fnDocAnchor returns the docsonnet anchor for the function with the given name, relative to the package that the
+// function is rendered in. nestedName is the path to the nested object containing the function (see
+// nestedBlockObject), and is empty for functions on the root object of the package.
+func fnDocAnchor(nestedName, fnName string) string {
+	return fmt.Sprintf("#fn-%s%s", strings.ToLower(nestedName), strings.ToLower(fnName))
+}
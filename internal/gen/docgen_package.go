@@ -25,6 +25,7 @@ var (
 )
 
 type rootDocStringData struct {
+	// ProviderDocURL is the URL to the provider docs, and is empty if unknown.
 	ProviderName   string
 	ProviderDocURL string
 }
@@ -34,6 +35,7 @@ type objectDocStringData struct {
 	ObjectName           string
 	Description          string
 	ResourceOrDataSource string
+	DocURL               string
 }
 
 func rootDocString(
@@ -53,12 +55,15 @@ func objectDocString(
 	providerName, typ string,
 	resrcOrDataSrc resourceOrDataSource,
 	schema *tfjson.SchemaBlock,
+	providerDocURL string,
 ) (string, error) {
+	objectName := nameWithoutProvider(providerName, typ)
 	data := objectDocStringData{
 		ProviderName:         providerName,
-		ObjectName:           nameWithoutProvider(providerName, typ),
+		ObjectName:           objectName,
 		ResourceOrDataSource: resrcOrDataSrc.String(),
 		Description:          schema.Description,
+		DocURL:               objectDocURL(providerDocURL, objectName, resrcOrDataSrc),
 	}
 
 	var out bytes.Buffer
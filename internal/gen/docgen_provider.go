@@ -39,14 +39,16 @@ var (
 type providerDocStringData struct {
 	ProviderName string
 	Description  string
+	DocURL       string
 }
 
 func providerDocString(
-	providerName, description string,
+	providerName, description, providerDocURL string,
 ) (string, error) {
 	data := providerDocStringData{
 		ProviderName: providerName,
 		Description:  description,
+		DocURL:       providerDocURL,
 	}
 
 	var out bytes.Buffer
@@ -61,11 +63,14 @@ func providerConstructorDocs(providerName string, schema *tfjson.SchemaBlock) (*
 		return nil, err
 	}
 
+	args := constructorDocArgs(schema)
+	for _, p := range providerParams {
+		args = append(args, docArg(p, "string", "null"))
+	}
 	docs := d.Func(
 		constructorFnName,
 		out.String(),
-		// TODO
-		nil,
+		args,
 	)
 	return &docs, nil
 }
@@ -80,8 +85,7 @@ func providerNewAttrsDocs(providerName string, schema *tfjson.SchemaBlock) (*j.T
 	docs := d.Func(
 		newAttrsFnName,
 		out.String(),
-		// TODO
-		nil,
+		constructorDocArgs(schema),
 	)
 	return &docs, nil
 }
@@ -105,12 +109,24 @@ func getProviderConstructorDocStringData(providerName string, schema *tfjson.Sch
 	sort.Strings(attrs)
 	for _, attr := range attrs {
 		cfg := attrMap[attr]
-		data.Params = append(data.Params, constructorDocStringParam{
-			Name:        attr,
-			Description: cfg.attr.Description,
-			Typ:         getAttrType(cfg.attr),
-			IsOptional:  cfg.attr.Optional,
-		})
+		param := constructorDocStringParam{
+			Name:         attr,
+			Description:  cfg.attr.Description,
+			Typ:          getAttrType(cfg.attr),
+			IsOptional:   cfg.attr.Optional,
+			IsDeprecated: cfg.attr.Deprecated,
+			IsSensitive:  cfg.attr.Sensitive,
+			IsWriteOnly:  cfg.attr.WriteOnly,
+		}
+		if cfg.attr.AttributeNestedType != nil {
+			param.IsNestedAttr = true
+			param.ParamConstructorRef = fmt.Sprintf(
+				"#fn-%s%snew",
+				strings.ToLower(strcase.ToCamel(providerName)),
+				strings.ToLower(strcase.ToCamel(attr)),
+			)
+		}
+		data.Params = append(data.Params, param)
 	}
 
 	blockMap := getNestedBlocks(schema)
@@ -122,11 +138,12 @@ func getProviderConstructorDocStringData(providerName string, schema *tfjson.Sch
 	for _, block := range blocks {
 		cfg := blockMap[block]
 		data.Params = append(data.Params, constructorDocStringParam{
-			Name:        block,
-			Description: cfg.block.Block.Description,
-			Typ:         getBlockType(cfg.block.NestingMode),
-			IsOptional:  true,
-			IsBlock:     true,
+			Name:         block,
+			Description:  cfg.block.Block.Description,
+			Typ:          getBlockType(cfg.block.NestingMode),
+			IsOptional:   true,
+			IsBlock:      true,
+			IsDeprecated: cfg.block.Block.Deprecated,
 			ParamConstructorRef: fmt.Sprintf(
 				"#fn-%s%snew",
 				strings.ToLower(strcase.ToCamel(providerName)),
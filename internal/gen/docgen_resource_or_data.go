@@ -11,7 +11,6 @@ import (
 
 	"github.com/Masterminds/sprig/v3"
 	tfjson "github.com/hashicorp/terraform-json"
-	"github.com/iancoleman/strcase"
 	j "github.com/jsonnet-libs/k8s/pkg/builder"
 	d "github.com/jsonnet-libs/k8s/pkg/builder/docsonnet"
 )
@@ -29,6 +28,18 @@ var (
 		template.New("docstring").Funcs(sprig.FuncMap()).Parse(attrsConstructorDocStringTmplContents),
 	)
 
+	//go:embed doctmpls/ref_docstring.md.tmpl
+	refDocStringTmplContents string
+	refDocStringTmpl         = template.Must(
+		template.New("docstring").Funcs(sprig.FuncMap()).Parse(refDocStringTmplContents),
+	)
+
+	//go:embed doctmpls/sensitive_attrs_docstring.md.tmpl
+	sensitiveAttrsDocStringTmplContents string
+	sensitiveAttrsDocStringTmpl         = template.Must(
+		template.New("docstring").Funcs(sprig.FuncMap()).Parse(sensitiveAttrsDocStringTmplContents),
+	)
+
 	//go:embed doctmpls/withfn_docstring.md.tmpl
 	withFnDocStringTmplContents string
 	withFnDocStringTmpl         = template.Must(
@@ -59,7 +70,19 @@ type constructorDocStringParam struct {
 	IsOptional  bool
 	IsBlock     bool
 
-	ParamConstructorRef string // only set on blocks
+	// IsNestedAttr is set on attributes with a nested attribute type.
+	IsNestedAttr bool
+
+	// IsDeprecated is set on attributes and blocks that are marked as deprecated in the provider schema.
+	IsDeprecated bool
+
+	// IsSensitive is set on attributes that are marked as sensitive in the provider schema.
+	IsSensitive bool
+
+	// IsWriteOnly is set on attributes that are marked as write-only in the provider schema.
+	IsWriteOnly bool
+
+	ParamConstructorRef string // only set on blocks and nested attributes
 }
 
 type withFnDocStringData struct {
@@ -73,25 +96,46 @@ type withFnDocStringData struct {
 	ResourceOrDataSource string
 	LabelParam           string
 
-	IsArray bool
-	IsMap   bool
-	IsMixin bool
+	IsArray   bool
+	IsMap     bool
+	IsMixin   bool
+	IsMixinAt bool
+	IsNested  bool
+
+	IsDeprecated bool
+	IsSensitive  bool
+	IsWriteOnly  bool
+
+	IsProvider bool
+
+	MetaArgURL  string   // only set on meta-arguments
+	TimeoutKeys []string // only set on the timeouts block
+
+	SetterFnName string
+	MixinFnName  string
+
+	// SetterFnRef and MixinFnRef are the docsonnet anchors of the setter and mixin flavors of the with function, for
+	// cross-linking between the two.
+	SetterFnRef string
+	MixinFnRef  string
 }
 
 func constructorDocs(
 	providerName, typ string,
 	resrcOrDataSrc resourceOrDataSource,
+	group string,
 	schema *tfjson.SchemaBlock,
 ) (*j.Type, error) {
-	docstr, err := constructorDocString(providerName, typ, resrcOrDataSrc, schema)
+	docstr, err := constructorDocString(providerName, typ, resrcOrDataSrc, group, schema)
 	if err != nil {
 		return nil, err
 	}
+	args := append([]j.Type{docArg(resrcOrDataSrc.labelArg(), "string", "")}, constructorDocArgs(schema)...)
+	args = append(args, docArg(metaParamName, "obj", "{}"))
 	doc := d.Func(
 		constructorFnName,
 		docstr,
-		// TODO: set args
-		nil,
+		args,
 	)
 	return &doc, nil
 }
@@ -99,9 +143,10 @@ func constructorDocs(
 func constructorDocString(
 	providerName, typ string,
 	resrcOrDataSrc resourceOrDataSource,
+	group string,
 	schema *tfjson.SchemaBlock,
 ) (string, error) {
-	data := getConstructorDocStringData(providerName, typ, resrcOrDataSrc, constructorFnName, "", schema)
+	data := getConstructorDocStringData(providerName, typ, resrcOrDataSrc, group, constructorFnName, "", schema)
 
 	var out bytes.Buffer
 	err := constructorDocStringTmpl.Execute(&out, data)
@@ -111,19 +156,19 @@ func constructorDocString(
 func attrsConstructorDocs(
 	providerName, typ string,
 	resrcOrDataSrc resourceOrDataSource,
+	group string,
 	fnName,
 	nestedName string,
 	schema *tfjson.SchemaBlock,
 ) (*j.Type, error) {
-	docstr, err := attrsConstructorDocString(providerName, typ, resrcOrDataSrc, fnName, nestedName, schema)
+	docstr, err := attrsConstructorDocString(providerName, typ, resrcOrDataSrc, group, fnName, nestedName, schema)
 	if err != nil {
 		return nil, err
 	}
 	doc := d.Func(
 		fnName,
 		docstr,
-		// TODO: set args
-		nil,
+		constructorDocArgs(schema),
 	)
 	return &doc, nil
 }
@@ -131,42 +176,175 @@ func attrsConstructorDocs(
 func attrsConstructorDocString(
 	providerName, typ string,
 	resrcOrDataSrc resourceOrDataSource,
+	group string,
 	fnName,
 	nestedName string,
 	schema *tfjson.SchemaBlock,
 ) (string, error) {
-	data := getConstructorDocStringData(providerName, typ, resrcOrDataSrc, fnName, nestedName, schema)
+	data := getConstructorDocStringData(providerName, typ, resrcOrDataSrc, group, fnName, nestedName, schema)
 
 	var out bytes.Buffer
 	err := attrsConstructorDocStringTmpl.Execute(&out, data)
 	return out.String(), err
 }
 
+type refDocStringData struct {
+	ObjectName           string
+	ResourceOrDataSource string
+	LabelParam           string
+
+	FnPrefix  string
+	RefPrefix string
+}
+
+func refDocs(
+	providerName, typ string,
+	resrcOrDataSrc resourceOrDataSource,
+	group string,
+) (*j.Type, error) {
+	objectName := nameWithoutProvider(providerName, typ)
+	data := refDocStringData{
+		ObjectName:           objectName,
+		ResourceOrDataSource: resrcOrDataSrc.String(),
+		LabelParam:           resrcOrDataSrc.labelArg(),
+		FnPrefix:             objectFnPrefix(providerName, objectName, resrcOrDataSrc, group),
+		RefPrefix:            typ,
+	}
+	if resrcOrDataSrc == IsDataSource {
+		data.RefPrefix = "data." + typ
+	}
+
+	var out bytes.Buffer
+	if err := refDocStringTmpl.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	doc := d.Func(
+		refFnName,
+		out.String(),
+		[]j.Type{docArg(data.LabelParam, "string", ""), docArg(attrArgName, "string", "")},
+	)
+	return &doc, nil
+}
+
+type sensitiveAttrsDocStringData struct {
+	FnPrefix             string
+	ObjectName           string
+	ResourceOrDataSource string
+}
+
+// sensitiveAttrsDocs returns the docsonnet docs for the sensitiveAttrs function. fnPrefix is the path to the object
+// containing the function in the library (e.g., PROVIDER.RESOURCE).
+func sensitiveAttrsDocs(
+	fnPrefix, objectName string,
+	resrcOrDataSrc resourceOrDataSource,
+) (*j.Type, error) {
+	data := sensitiveAttrsDocStringData{
+		FnPrefix:             fnPrefix,
+		ObjectName:           objectName,
+		ResourceOrDataSource: resrcOrDataSrc.String(),
+	}
+
+	var out bytes.Buffer
+	if err := sensitiveAttrsDocStringTmpl.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	doc := d.Func(
+		sensitiveAttrsFnName,
+		out.String(),
+		[]j.Type{},
+	)
+	return &doc, nil
+}
+
 func withFnDocs(
 	providerName, objectName string,
 	resrcOrDataSrc resourceOrDataSource,
+	group string,
+	nestedName string,
 	attrOrBlockName string,
 	typ string,
 	collTyp collectionType,
-	isMixin bool,
+	labels fieldLabels,
+	flavor withFnFlavor,
 ) (*j.Type, error) {
-	fnName := fmt.Sprintf("with%s", strcase.ToCamel(attrOrBlockName))
-	if isMixin {
-		fnName = fnName + "Mixin"
-	}
+	fnName := flavor.fnName(attrOrBlockName)
 
 	docstr, err := withFnDocString(
-		providerName, nameWithoutProvider(providerName, typ), resrcOrDataSrc,
-		attrOrBlockName, fnName, typ, collTyp,
+		providerName, nameWithoutProvider(providerName, objectName), resrcOrDataSrc, group, nestedName,
+		attrOrBlockName, fnName, typ, collTyp, labels, flavor,
 	)
 	if err != nil {
 		return nil, err
 	}
+	// The with functions on nested blocks are relative to the block, and thus do not take in the label.
+	labelArg := resrcOrDataSrc.labelArg()
+	if resrcOrDataSrc == IsNestedBlock {
+		labelArg = ""
+	}
 	doc := d.Func(
 		fnName,
 		docstr,
-		// TODO
-		nil,
+		withFnDocArgs(labelArg, typ, flavor),
+	)
+	return &doc, nil
+}
+
+// metaArgWithFnDocs returns the docsonnet docs for the with function of the given flavor for a meta-argument. This uses
+// the same template as the with functions for attributes and blocks, with an additional link to the Terraform docs of
+// the meta-argument.
+func metaArgWithFnDocs(
+	providerName, objectName string,
+	resrcOrDataSrc resourceOrDataSource,
+	group string,
+	arg metaArgument,
+	flavor withFnFlavor,
+) (*j.Type, error) {
+	fnName := flavor.fnName(arg.tfName)
+
+	data := getWithFnDocStringData(
+		providerName, nameWithoutProvider(providerName, objectName), resrcOrDataSrc, group, "", arg.tfName, fnName,
+		arg.typ, arg.collTyp == IsListOrSet, arg.collTyp == IsMap, flavor,
+	)
+	data.MetaArgURL = arg.docsURL
+	return withFnDocsFromData(data)
+}
+
+// timeoutsWithFnDocs returns the docsonnet docs for the with function of the given flavor for the conventional
+// timeouts block, listing the operation keys that can be set.
+func timeoutsWithFnDocs(
+	providerName, objectName string,
+	resrcOrDataSrc resourceOrDataSource,
+	group string,
+	keys []string,
+	flavor withFnFlavor,
+) (*j.Type, error) {
+	fnName := flavor.fnName(timeoutsBlockName)
+
+	data := getWithFnDocStringData(
+		providerName, nameWithoutProvider(providerName, objectName), resrcOrDataSrc, group, "", timeoutsBlockName, fnName,
+		"obj", false, true, flavor,
+	)
+	data.TimeoutKeys = keys
+	return withFnDocsFromData(data)
+}
+
+// withFnDocsFromData renders the docsonnet docs for a with function from the given template data.
+func withFnDocsFromData(data withFnDocStringData) (*j.Type, error) {
+	var out bytes.Buffer
+	if err := withFnDocStringTmpl.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	flavor := IsSetter
+	switch {
+	case data.IsMixin:
+		flavor = IsMixin
+	case data.IsMixinAt:
+		flavor = IsMixinAt
+	}
+	doc := d.Func(
+		data.FnName,
+		out.String(),
+		withFnDocArgs(data.LabelParam, data.Typ, flavor),
 	)
 	return &doc, nil
 }
@@ -175,15 +353,22 @@ func withFnDocs(
 func withFnDocString(
 	providerName, objectName string,
 	resrcOrDataSrc resourceOrDataSource,
+	group string,
+	nestedName string,
 	attrOrBlockName string,
 	fnName string,
 	typ string,
 	collTyp collectionType,
+	labels fieldLabels,
+	flavor withFnFlavor,
 ) (string, error) {
 	data := getWithFnDocStringData(
-		providerName, objectName, resrcOrDataSrc, attrOrBlockName, fnName, typ,
-		collTyp == IsListOrSet, collTyp == IsMap,
+		providerName, objectName, resrcOrDataSrc, group, nestedName, attrOrBlockName, fnName, typ,
+		collTyp == IsListOrSet, collTyp == IsMap, flavor,
 	)
+	data.IsDeprecated = labels.isDeprecated
+	data.IsSensitive = labels.isSensitive
+	data.IsWriteOnly = labels.isWriteOnly
 
 	var out bytes.Buffer
 	err := withFnDocStringTmpl.Execute(&out, data)
@@ -193,6 +378,7 @@ func withFnDocString(
 func getConstructorDocStringData(
 	providerName, typ string,
 	resrcOrDataSrc resourceOrDataSource,
+	group string,
 	fnName,
 	nestedName string,
 	schema *tfjson.SchemaBlock,
@@ -206,12 +392,11 @@ func getConstructorDocStringData(
 		LabelParam:           resrcOrDataSrc.labelArg(),
 		FnName:               fnName,
 		CoreFnRef:            getCoreFnRef(resrcOrDataSrc),
-		FnPrefix:             fmt.Sprintf("%s.%s", providerName, objectName),
+		FnPrefix:             objectFnPrefix(providerName, objectName, resrcOrDataSrc, group),
 		RefPrefix:            fmt.Sprintf("%s_%s", providerName, objectName),
 		ConstructorRef:       "#fn-new",
 	}
 	if resrcOrDataSrc == IsDataSource {
-		data.FnPrefix = fmt.Sprintf("%s.data.%s", providerName, objectName)
 		data.RefPrefix = fmt.Sprintf("data_%s_%s", providerName, objectName)
 	}
 
@@ -223,12 +408,24 @@ func getConstructorDocStringData(
 	sort.Strings(attrs)
 	for _, attr := range attrs {
 		cfg := attrMap[attr]
-		data.Params = append(data.Params, constructorDocStringParam{
-			Name:        attr,
-			Description: cfg.attr.Description,
-			Typ:         getAttrType(cfg.attr),
-			IsOptional:  cfg.attr.Optional,
-		})
+		param := constructorDocStringParam{
+			Name:         attr,
+			Description:  cfg.attr.Description,
+			Typ:          getAttrType(cfg.attr),
+			IsOptional:   cfg.attr.Optional,
+			IsDeprecated: cfg.attr.Deprecated,
+			IsSensitive:  cfg.attr.Sensitive,
+			IsWriteOnly:  cfg.attr.WriteOnly,
+		}
+		if cfg.attr.AttributeNestedType != nil {
+			param.IsNestedAttr = true
+			param.ParamConstructorRef = fmt.Sprintf(
+				"#fn-%s%snew",
+				strings.ToLower(nestedName),
+				strings.ToLower(attr),
+			)
+		}
+		data.Params = append(data.Params, param)
 	}
 
 	blockMap := getNestedBlocks(schema)
@@ -240,11 +437,12 @@ func getConstructorDocStringData(
 	for _, block := range blocks {
 		cfg := blockMap[block]
 		data.Params = append(data.Params, constructorDocStringParam{
-			Name:        block,
-			Description: cfg.block.Block.Description,
-			Typ:         getBlockType(cfg.block.NestingMode),
-			IsOptional:  true,
-			IsBlock:     true,
+			Name:         block,
+			Description:  cfg.block.Block.Description,
+			Typ:          getBlockType(cfg.block.NestingMode),
+			IsOptional:   true,
+			IsBlock:      true,
+			IsDeprecated: cfg.block.Block.Deprecated,
 			ParamConstructorRef: fmt.Sprintf(
 				"#fn-%s%snew",
 				strings.ToLower(nestedName),
@@ -255,30 +453,47 @@ func getConstructorDocStringData(
 	return data
 }
 
+// getWithFnDocStringData returns the template data for the docs of a with function. group is the group of the resource
+// or data source in the library (see renderOpts.group). nestedName is the path to the nested object containing the with
+// function (see nestedBlockObject), and is used to construct the docsonnet anchors for cross-linking between the with
+// function flavors.
 func getWithFnDocStringData(
 	providerName, objectName string,
 	resrcOrDataSrc resourceOrDataSource,
+	group string,
+	nestedName string,
 	attrOrBlockName string,
 	fnName string,
 	typ string,
 	isArray, isMap bool,
+	flavor withFnFlavor,
 ) withFnDocStringData {
-	isMixin := strings.HasSuffix(fnName, "Mixin")
-
 	data := withFnDocStringData{
-		AttrOrBlockName: attrOrBlockName,
-		ObjectName:      objectName,
-		Typ:             typ,
-		FnPrefix: fmt.Sprintf(
-			"%s.%s",
-			providerName, objectName,
-		),
+		AttrOrBlockName:      attrOrBlockName,
+		ObjectName:           objectName,
+		Typ:                  typ,
+		FnPrefix:             objectFnPrefix(providerName, objectName, resrcOrDataSrc, group),
 		ResourceOrDataSource: resrcOrDataSrc.String(),
 		LabelParam:           resrcOrDataSrc.labelArg(),
 		FnName:               fnName,
 		IsArray:              isArray,
 		IsMap:                isMap,
-		IsMixin:              isMixin,
+		IsMixin:              flavor == IsMixin,
+		IsMixinAt:            flavor == IsMixinAt,
+		SetterFnName:         IsSetter.fnName(attrOrBlockName),
+		MixinFnName:          IsMixin.fnName(attrOrBlockName),
+		SetterFnRef:          fnDocAnchor(nestedName, IsSetter.fnName(attrOrBlockName)),
+		MixinFnRef:           fnDocAnchor(nestedName, IsMixin.fnName(attrOrBlockName)),
+	}
+	switch resrcOrDataSrc {
+	case IsNestedBlock:
+		// The with functions on nested blocks are relative to the block, and thus do not take in the label.
+		data.IsNested = true
+		data.LabelParam = ""
+	case IsProvider:
+		// The provider with functions live on the provider object, and target the provider block by alias.
+		data.IsProvider = true
+		data.FnPrefix = fmt.Sprintf("%s.provider", providerName)
 	}
 	return data
 }
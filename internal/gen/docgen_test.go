@@ -4,6 +4,9 @@ import (
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestDocStringResourceConsructor(t *testing.T) {
@@ -14,7 +17,7 @@ func TestDocStringResourceConsructor(t *testing.T) {
 	complexResource := schema.ResourceSchemas["tfcoremock_complex_resource"]
 	out, err := constructorDocString(
 		"tfcoremock", "tfcoremock_complex_resource",
-		IsResource, complexResource.Block,
+		IsResource, "", complexResource.Block,
 	)
 	g.Expect(err).NotTo(HaveOccurred())
 	t.Logf(out)
@@ -28,8 +31,120 @@ func TestDocStringResourceAttrsConstructor(t *testing.T) {
 	complexResource := schema.ResourceSchemas["tfcoremock_complex_resource"]
 	out, err := attrsConstructorDocString(
 		"tfcoremock", "tfcoremock_complex_resource",
-		IsResource, "newAttrs", "", complexResource.Block,
+		IsResource, "", "newAttrs", "", complexResource.Block,
 	)
 	g.Expect(err).NotTo(HaveOccurred())
 	t.Logf(out)
 }
+
+func TestProviderDocURL(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		src      string
+		version  string
+		expected string
+	}{
+		{"registry.terraform.io/hashicorp/null", "3.2.1", "https://registry.terraform.io/providers/hashicorp/null/3.2.1/docs"},
+		{"DopplerHQ/doppler", "", "https://registry.terraform.io/providers/dopplerhq/doppler/latest/docs"},
+		{"registry.opentofu.org/hashicorp/null", "3.2.1", "https://registry.terraform.io/providers/hashicorp/null/3.2.1/docs"},
+		{"registry.opentofu.org/dopplerhq/doppler", "", "https://registry.terraform.io/providers/dopplerhq/doppler/latest/docs"},
+		{"example.com/acme/internal", "1.0.0", ""},
+		{"", "3.2.1", ""},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.src, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			out, err := providerDocURL(tc.src, tc.version)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(out).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestResolveProviderDocURL(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		opts        RenderLibraryOpts
+		expected    string
+		expectWarns bool
+	}{
+		{
+			"terraform_registry",
+			RenderLibraryOpts{ProviderSrc: "registry.terraform.io/hashicorp/null", ProviderVersion: "3.2.1"},
+			"https://registry.terraform.io/providers/hashicorp/null/3.2.1/docs",
+			false,
+		},
+		{
+			"opentofu_registry",
+			RenderLibraryOpts{ProviderSrc: "registry.opentofu.org/hashicorp/null", ProviderVersion: "3.2.1"},
+			"https://registry.terraform.io/providers/hashicorp/null/3.2.1/docs",
+			false,
+		},
+		{
+			"unknown_registry",
+			RenderLibraryOpts{ProviderSrc: "example.com/acme/internal", ProviderVersion: "1.0.0"},
+			"",
+			true,
+		},
+		{
+			"unknown_registry_with_override",
+			RenderLibraryOpts{ProviderSrc: "example.com/acme/internal", ProviderDocURL: "https://docs.example.com/internal"},
+			"https://docs.example.com/internal",
+			false,
+		},
+		{
+			"no_src",
+			RenderLibraryOpts{},
+			"",
+			false,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			core, logs := observer.New(zapcore.WarnLevel)
+			out, err := resolveProviderDocURL(zap.New(core).Sugar(), tc.opts)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(out).To(Equal(tc.expected))
+			if tc.expectWarns {
+				g.Expect(logs.FilterMessageSnippet("Could not derive the registry docs URL").Len()).To(Equal(1))
+			} else {
+				g.Expect(logs.Len()).To(Equal(0))
+			}
+		})
+	}
+}
+
+func TestRenderResourceDocLinks(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	complexResource := schema.ResourceSchemas["tfcoremock_complex_resource"]
+	opts := renderOpts{providerDocURL: "https://registry.terraform.io/providers/hashicorp/tfcoremock/0.1.2/docs"}
+
+	jt, err := renderResourceOrDataSource(
+		"tfcoremock", "tfcoremock_complex_resource", IsResource, complexResource.Block, opts,
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	out := jt.String()
+	g.Expect(out).To(ContainSubstring(
+		"(https://registry.terraform.io/providers/hashicorp/tfcoremock/0.1.2/docs/resources/complex_resource)",
+	))
+	g.Expect(out).To(ContainSubstring("[tfcoremock.complex_resource.withListBlockMixin](#fn-withlistblockmixin)"))
+	g.Expect(out).To(ContainSubstring("[tfcoremock.complex_resource.withListBlock](#fn-withlistblock)"))
+	g.Expect(out).To(ContainSubstring(
+		"[tfcoremock.complex_resource.list_block.withListBlockMixin](#fn-list_blockwithlistblockmixin)",
+	))
+	g.Expect(out).NotTo(ContainSubstring("(TODO)"))
+}
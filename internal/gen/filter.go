@@ -0,0 +1,109 @@
+package gen
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// nameFilter filters resource and data source type names (e.g., aws_s3_bucket) with include and exclude patterns. A
+// pattern wrapped in slashes (e.g., /^aws_s3_.*$/) is a regular expression, and any other pattern is a glob (e.g.,
+// aws_s3_*) that must match the whole name.
+type nameFilter struct {
+	include []func(string) bool
+	exclude []func(string) bool
+}
+
+// newNameFilter compiles the given include and exclude patterns into a nameFilter. When include is empty, all the
+// names that are not excluded are included.
+func newNameFilter(include, exclude []string) (*nameFilter, error) {
+	f := &nameFilter{}
+	for _, p := range include {
+		m, err := compileNamePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, m)
+	}
+	for _, p := range exclude {
+		m, err := compileNamePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, m)
+	}
+	return f, nil
+}
+
+// matches returns whether the given name matches at least one of the include patterns (if any), and none of the
+// exclude patterns.
+func (f *nameFilter) matches(name string) bool {
+	if len(f.include) > 0 && !anyMatch(f.include, name) {
+		return false
+	}
+	return !anyMatch(f.exclude, name)
+}
+
+func anyMatch(matchers []func(string) bool, name string) bool {
+	for _, m := range matchers {
+		if m(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileNamePattern(p string) (func(string) bool, error) {
+	if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+		re, err := regexp.Compile(p[1 : len(p)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern %s: %w", p, err)
+		}
+		return re.MatchString, nil
+	}
+
+	// Validate the glob pattern upfront, since path.Match only reports malformed patterns when they are matched.
+	if _, err := path.Match(p, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %s: %w", p, err)
+	}
+	return func(name string) bool {
+		// The error can be ignored since the pattern was validated above.
+		matched, _ := path.Match(p, name)
+		return matched
+	}, nil
+}
+
+// schemaWithFilters returns a copy of the given provider schema that only contains the resources and data sources that
+// match the filters in opts. When opts.SkipDataSources is set, all the data sources are removed.
+func schemaWithFilters(schema *tfjson.ProviderSchema, opts RenderLibraryOpts) (*tfjson.ProviderSchema, error) {
+	resrcFilter, err := newNameFilter(opts.IncludeResources, opts.ExcludeResources)
+	if err != nil {
+		return nil, err
+	}
+	dataSrcFilter, err := newNameFilter(opts.IncludeDataSources, opts.ExcludeDataSources)
+	if err != nil {
+		return nil, err
+	}
+
+	out := *schema
+	out.ResourceSchemas = filterSchemas(schema.ResourceSchemas, resrcFilter)
+	if opts.SkipDataSources {
+		out.DataSourceSchemas = map[string]*tfjson.Schema{}
+	} else {
+		out.DataSourceSchemas = filterSchemas(schema.DataSourceSchemas, dataSrcFilter)
+	}
+	return &out, nil
+}
+
+func filterSchemas(schemas map[string]*tfjson.Schema, f *nameFilter) map[string]*tfjson.Schema {
+	out := map[string]*tfjson.Schema{}
+	for name, s := range schemas {
+		if f.matches(name) {
+			out[name] = s
+		}
+	}
+	return out
+}
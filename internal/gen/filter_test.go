@@ -0,0 +1,135 @@
+package gen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+)
+
+func TestNameFilter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected map[string]bool
+	}{
+		{
+			name: "no patterns",
+			expected: map[string]bool{
+				"aws_s3_bucket": true,
+				"aws_instance":  true,
+			},
+		},
+		{
+			name:    "glob include",
+			include: []string{"aws_s3_*"},
+			expected: map[string]bool{
+				"aws_s3_bucket":     true,
+				"aws_instance":      false,
+				"aws_s3control_foo": false,
+			},
+		},
+		{
+			name:    "regex include and glob exclude",
+			include: []string{"/^aws_(s3|ec2)_/"},
+			exclude: []string{"aws_s3_bucket_*"},
+			expected: map[string]bool{
+				"aws_s3_bucket":        true,
+				"aws_s3_bucket_policy": false,
+				"aws_ec2_host":         true,
+				"aws_instance":         false,
+			},
+		},
+		{
+			name:    "exclude only",
+			exclude: []string{"/deprecated/"},
+			expected: map[string]bool{
+				"aws_instance":            true,
+				"aws_deprecated_instance": false,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			f, err := newNameFilter(tc.include, tc.exclude)
+			g.Expect(err).NotTo(HaveOccurred())
+			for name, expected := range tc.expected {
+				g.Expect(f.matches(name)).To(Equal(expected), name)
+			}
+		})
+	}
+}
+
+func TestNameFilterInvalidPattern(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	_, err := newNameFilter([]string{"aws_[s3"}, nil)
+	g.Expect(err).To(MatchError(ContainSubstring("invalid glob pattern")))
+	_, err = newNameFilter(nil, []string{"/aws_(s3/"})
+	g.Expect(err).To(MatchError(ContainSubstring("invalid regex pattern")))
+}
+
+func TestSchemaWithFilters(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	out, err := schemaWithFilters(schema, RenderLibraryOpts{
+		IncludeResources: []string{"tfcoremock_simple_*"},
+		SkipDataSources:  true,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(out.ResourceSchemas).To(HaveLen(1))
+	g.Expect(out.ResourceSchemas).To(HaveKey("tfcoremock_simple_resource"))
+	g.Expect(out.DataSourceSchemas).To(BeEmpty())
+
+	// The original schema should be left untouched.
+	g.Expect(schema.ResourceSchemas).To(HaveKey("tfcoremock_complex_resource"))
+	g.Expect(schema.DataSourceSchemas).NotTo(BeEmpty())
+
+	out, err = schemaWithFilters(schema, RenderLibraryOpts{
+		ExcludeDataSources: []string{"/complex/"},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(out.ResourceSchemas).To(Equal(schema.ResourceSchemas))
+	g.Expect(out.DataSourceSchemas).To(HaveKey("tfcoremock_simple_resource"))
+	g.Expect(out.DataSourceSchemas).NotTo(HaveKey("tfcoremock_complex_resource"))
+}
+
+func TestRenderLibraryWithFilters(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+	logger := logging.GetSugaredLoggerForTest()
+
+	libDir := t.TempDir()
+	opts := RenderLibraryOpts{
+		ProviderName:     "tfcoremock",
+		Schema:           loadSchema(g, tfcoremockSchemaF),
+		ExcludeResources: []string{"tfcoremock_complex_resource"},
+		SkipDataSources:  true,
+	}
+	g.Expect(RenderLibrary(logger, context.Background(), libDir, opts)).To(Succeed())
+
+	genDir := filepath.Join(libDir, libRootDirName)
+	g.Expect(filepath.Join(genDir, libResourcesDirName, "simple_resource.libsonnet")).To(BeARegularFile())
+	g.Expect(filepath.Join(genDir, libResourcesDirName, "complex_resource.libsonnet")).NotTo(BeAnExistingFile())
+	g.Expect(filepath.Join(genDir, libDataSourcesDirName)).NotTo(BeAnExistingFile())
+
+	idx, err := os.ReadFile(filepath.Join(genDir, mainLibsonnetName))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(idx)).To(ContainSubstring("simple_resource"))
+	g.Expect(string(idx)).NotTo(ContainSubstring("complex_resource"))
+	g.Expect(string(idx)).NotTo(ContainSubstring("data/main.libsonnet"))
+}
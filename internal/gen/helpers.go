@@ -10,6 +10,7 @@ import (
 	"github.com/google/go-jsonnet/formatter"
 	tfjson "github.com/hashicorp/terraform-json"
 	tfaddr "github.com/hashicorp/terraform-registry-address"
+	"github.com/iancoleman/strcase"
 	j "github.com/jsonnet-libs/k8s/pkg/builder"
 	"go.uber.org/zap"
 )
@@ -38,13 +39,47 @@ func getCollectionType(nestingMode tfjson.SchemaNestingMode) collectionType {
 	switch nestingMode {
 	case tfjson.SchemaNestingModeList, tfjson.SchemaNestingModeSet:
 		return IsListOrSet
-	// TODO: nested object type should be special cased since it is a typed map
+	// Both single objects and maps of objects keyed by name are represented as jsonnet objects, so they can be merged
+	// the same way.
 	case tfjson.SchemaNestingModeMap, tfjson.SchemaNestingModeSingle, tfjson.SchemaNestingModeGroup:
 		return IsMap
 	}
 	panic(fmt.Errorf("Unsupported nesting mode: %s", nestingMode))
 }
 
+type withFnFlavor uint8
+
+const (
+	IsSetter withFnFlavor = iota
+	IsMixin
+	IsMixinAt
+)
+
+func (f withFnFlavor) String() string {
+	switch f {
+	case IsSetter:
+		return "IsSetter"
+	case IsMixin:
+		return "IsMixin"
+	case IsMixinAt:
+		return "IsMixinAt"
+	}
+	return unknown
+}
+
+// fnName returns the name of the with function of this flavor for the given attribute or block. The function names
+// are of the form withATTR (IsSetter), withATTRMixin (IsMixin), and withATTRMixinAt (IsMixinAt).
+func (f withFnFlavor) fnName(attrOrBlockName string) string {
+	fnName := fmt.Sprintf("with%s", strcase.ToCamel(attrOrBlockName))
+	switch f {
+	case IsMixin:
+		return fnName + "Mixin"
+	case IsMixinAt:
+		return fnName + "MixinAt"
+	}
+	return fnName
+}
+
 type resourceOrDataSource uint8
 
 const (
@@ -63,7 +98,13 @@ const (
 	resourceInjectAttrName   = "resource"
 	dataSourceLabelArg       = "dataSrcLabel"
 	dataSourceInjectAttrName = "data"
+	providerAliasArg         = "alias"
+	providerInjectAttrName   = "provider"
+	coreImportPath           = "github.com/tf-libsonnet/core/main.libsonnet"
+	docsonnetImportPath      = "github.com/jsonnet-libs/docsonnet/doc-util/main.libsonnet"
 	metaParamName            = "_meta"
+	valueArgName             = "value"
+	indexArgName             = "index"
 	unknown                  = "__UNKNOWN__"
 )
 
@@ -87,6 +128,8 @@ func (resrcOrDataSrc resourceOrDataSource) labelArg() string {
 		return resourceLabelArg
 	case IsDataSource:
 		return dataSourceLabelArg
+	case IsProvider:
+		return providerAliasArg
 	}
 	return unknown
 }
@@ -97,10 +140,61 @@ func (resrcOrDataSrc resourceOrDataSource) injectAttrName() string {
 		return resourceInjectAttrName
 	case IsDataSource:
 		return dataSourceInjectAttrName
+	case IsProvider:
+		return providerInjectAttrName
 	}
 	return unknown
 }
 
+// renderOpts are the options that control how the libsonnet code is rendered for each provider, resource, or data source.
+type renderOpts struct {
+	// withAssertions adds type assertions on the params of the generated constructors.
+	withAssertions bool
+
+	// warnDeprecated makes the generated functions emit a warning with std.trace when a deprecated attribute or block is
+	// set.
+	warnDeprecated bool
+
+	// withSensitiveAttrs adds a function to list the sensitive attributes of each provider, resource, and data source.
+	withSensitiveAttrs bool
+
+	// providerDocURL is the URL to the docs of the provider, which is linked from the generated docs. The docs of each
+	// resource and data source link to the corresponding page under this URL (see objectDocURL).
+	providerDocURL string
+
+	// group is the group that the resource or data source is placed in when grouping by name segment (see objectGroup),
+	// which is part of the path to the object in the library. This is empty when the object is not grouped.
+	group string
+}
+
+// objectFnPrefix returns the path to the given resource or data source in the generated library, which prefixes the
+// functions in the docs (e.g., aws.s3_bucket or aws.data.s3_bucket). When the object is grouped, the group is nested
+// under the provider (e.g., aws.s3.s3_bucket). For nested blocks, providerName is the path to the parent object, and the
+// group is ignored since it is already part of that path.
+func objectFnPrefix(providerName, objectName string, resrcOrDataSrc resourceOrDataSource, group string) string {
+	path := []string{providerName}
+	if resrcOrDataSrc == IsNestedBlock {
+		return strings.Join(append(path, objectName), ".")
+	}
+	if resrcOrDataSrc == IsDataSource {
+		path = append(path, libDataSourcesDirName)
+	}
+	if group != "" {
+		path = append(path, group)
+	}
+	return strings.Join(append(path, objectName), ".")
+}
+
+// qualifiedObjectName returns the name of the object to use in the messages of the generated code (e.g., assertions and
+// warnings). This is the Terraform type for resources and data sources, and the path to the nested block object for
+// nested blocks.
+func qualifiedObjectName(providerName, typ string, resrcOrDataSrc resourceOrDataSource) string {
+	if resrcOrDataSrc == IsNestedBlock {
+		return fmt.Sprintf("%s.%s", providerName, typ)
+	}
+	return typ
+}
+
 type sortedTypeList []j.Type
 
 // START sort interface
@@ -200,12 +294,20 @@ func constructorParamList(schema *tfjson.SchemaBlock) paramList {
 
 // importCore returns the import call for importing the core library.
 func importCore() j.LocalType {
-	return j.Local(j.Import("tf", "github.com/tf-libsonnet/core/main.libsonnet"))
+	return j.Local(j.Import("tf", coreImportPath))
 }
 
 // improtDocsonnet returns the import call for importing the docsonnet library.
 func importDocsonnet() j.LocalType {
-	return j.Local(j.Import("d", "github.com/jsonnet-libs/docsonnet/doc-util/main.libsonnet"))
+	return j.Local(j.Import("d", docsonnetImportPath))
+}
+
+// fieldLabels are the flags from the provider schema that are surfaced on an attribute or block in the generated code
+// and docs.
+type fieldLabels struct {
+	isDeprecated bool
+	isSensitive  bool
+	isWriteOnly  bool
 }
 
 type attribute struct {
@@ -234,11 +336,30 @@ func getInputAttributes(schema *tfjson.SchemaBlock) map[string]*attribute {
 	return out
 }
 
+// labels returns the flags from the provider schema that should be surfaced for the attribute.
+func (a *attribute) labels() fieldLabels {
+	return fieldLabels{
+		isDeprecated: a.attr.Deprecated,
+		isSensitive:  a.attr.Sensitive,
+		isWriteOnly:  a.attr.WriteOnly,
+	}
+}
+
 type block struct {
 	tfName string
 	block  *tfjson.SchemaBlockType
 }
 
+// isDeprecated returns whether the block is marked as deprecated in the provider schema.
+func (b *block) isDeprecated() bool {
+	return b.block.Block != nil && b.block.Block.Deprecated
+}
+
+// labels returns the flags from the provider schema that should be surfaced for the block.
+func (b *block) labels() fieldLabels {
+	return fieldLabels{isDeprecated: b.isDeprecated()}
+}
+
 func getNestedBlocks(schema *tfjson.SchemaBlock) map[string]*block {
 	out := map[string]*block{}
 	for name, cfg := range schema.NestedBlocks {
@@ -250,6 +371,45 @@ func getNestedBlocks(schema *tfjson.SchemaBlock) map[string]*block {
 	return out
 }
 
+// getNestedAttributeTypes returns the input attributes that have a nested attribute type (as used heavily by plugin
+// framework providers). The nested attribute types are represented as blocks so that they can be rendered with the same
+// constructor objects as nested blocks.
+func getNestedAttributeTypes(schema *tfjson.SchemaBlock) map[string]*block {
+	out := map[string]*block{}
+	for name, cfg := range getInputAttributes(schema) {
+		nested := cfg.attr.AttributeNestedType
+		if nested == nil {
+			continue
+		}
+
+		out[name] = &block{
+			tfName: cfg.tfName,
+			block: &tfjson.SchemaBlockType{
+				NestingMode: nested.NestingMode,
+				MinItems:    nested.MinItems,
+				MaxItems:    nested.MaxItems,
+				Block: &tfjson.SchemaBlock{
+					Attributes:      nested.Attributes,
+					Description:     cfg.attr.Description,
+					DescriptionKind: cfg.attr.DescriptionKind,
+					Deprecated:      cfg.attr.Deprecated,
+				},
+			},
+		}
+	}
+	return out
+}
+
+// getNestedObjects returns all the nested blocks and nested attribute types of the schema, which are all rendered as
+// objects with constructors.
+func getNestedObjects(schema *tfjson.SchemaBlock) map[string]*block {
+	out := getNestedBlocks(schema)
+	for name, cfg := range getNestedAttributeTypes(schema) {
+		out[name] = cfg
+	}
+	return out
+}
+
 // sanitizeForRef sanitizes attribute names that use reserved Jsonnet words like local and import so that they don't
 // cause syntax errors. If the name is a reserved Jsonnet word, this will return the name with an _ suffix.
 func sanitizeForRef(name string) string {
@@ -266,10 +426,10 @@ func sanitizeForRef(name string) string {
 	return name
 }
 
-// writeDocToFile writes the given jsonnet document to a file. Note that this
+// writeDocToFile writes the given jsonnet document to a file, prefixed with the given header comment. Note that this
 // runs the document through the jsonnet-fmt prior to saving to disk.
-func writeDocToFile(logger *zap.SugaredLogger, doc *j.Doc, fpath string) error {
-	docStr := doc.String()
+func writeDocToFile(logger *zap.SugaredLogger, doc *j.Doc, header, fpath string) error {
+	docStr := header + doc.String()
 	docFmted, err := formatter.Format("", docStr, formatter.DefaultOptions())
 	if err != nil {
 		logger.Errorf("Error formatting %s", fpath)
@@ -0,0 +1,27 @@
+// A minimal implementation of the docsonnet doc-util library that is used to evaluate the docsonnet metadata of the
+// generated libraries for rendering the Markdown docs, without requiring the library to be vendored with jb.
+//
+// Unlike doc-util, args record whether a default was set so that optional params with a null default are rendered in
+// the function signatures.
+local noDefault = { '__noDefault__': true };
+{
+  pkg(name, url, help, filename='', version=''):: { name: name, 'import': url, help: help },
+  obj(help, fields={}):: { object: { help: help } },
+  fn(help, args=[]):: { 'function': { help: help, args: args } },
+  arg(name, type, default=noDefault, enums=null):: {
+    name: name,
+    type: type,
+    hasDefault: default != noDefault,
+    default: if default != noDefault then default else null,
+  },
+  T:: {
+    any: 'any',
+    array: 'array',
+    boolean: 'bool',
+    'function': 'function',
+    'null': 'null',
+    number: 'number',
+    object: 'object',
+    string: 'string',
+  },
+}
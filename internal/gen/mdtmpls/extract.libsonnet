@@ -0,0 +1,33 @@
+// extract walks the docsonnet metadata of the given package object, returning the package docs along with the docs of
+// each function. The nested objects (e.g., for nested blocks) are walked recursively, with the path to each function
+// recorded so that the anchors match the ones used in the docstrings.
+local hasDocs(obj) = std.length([k for k in std.objectFieldsAll(obj) if std.startsWith(k, '#')]) > 0;
+
+local fields(obj, path) =
+  [
+    local name = std.substr(k, 1, std.length(k) - 1);
+    local doc = obj[k];
+    {
+      name: name,
+      path: path + name,
+      help: doc['function'].help,
+      args: doc['function'].args,
+    }
+    for k in std.objectFieldsAll(obj)
+    if std.startsWith(k, '#') && k != '#' && std.objectHas(obj[k], 'function')
+  ];
+
+local objects(obj, path) =
+  std.flattenArrays([
+    local nested = obj[k];
+    [{ path: path + k, fns: fields(nested, path + k + '.') }] + objects(nested, path + k + '.')
+    for k in std.objectFieldsAll(obj)
+    if !std.startsWith(k, '#') && std.isObject(obj[k]) && hasDocs(obj[k])
+  ]);
+
+function(pkg) {
+  name: pkg['#'].name,
+  help: pkg['#'].help,
+  fns: fields(pkg, ''),
+  objects: objects(pkg, ''),
+}
@@ -0,0 +1,105 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	metadataFileName = "metadata.json"
+	schemaHashPrefix = "sha256:"
+)
+
+// LibraryMetadata records how a library was generated, so that downstream repos can audit and reproduce the
+// generation. This is rendered to the _gen/metadata.json file of the library.
+type LibraryMetadata struct {
+	// GeneratorVersion is the version of libgenerator that generated the library.
+	GeneratorVersion string `json:"generator_version"`
+
+	// ProviderSrc is the fully qualified source of the provider (e.g., registry.terraform.io/hashicorp/null).
+	ProviderSrc string `json:"provider_src"`
+
+	// ProviderVersion is the exact version of the provider that the schema was retrieved from.
+	ProviderVersion string `json:"provider_version"`
+
+	// TerraformVersion is the version of the CLI that was used to retrieve the schema.
+	TerraformVersion string `json:"terraform_version"`
+
+	// SchemaHash is the sha256 hash of the provider schema that the library was generated from.
+	SchemaHash string `json:"schema_hash"`
+
+	// GeneratedAt is the time when the library was generated.
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// newLibraryMetadata constructs the metadata for the library that is rendered with the given options.
+func newLibraryMetadata(opts RenderLibraryOpts) (LibraryMetadata, error) {
+	schemaJSON, err := json.Marshal(opts.Schema)
+	if err != nil {
+		return LibraryMetadata{}, err
+	}
+	sum := sha256.Sum256(schemaJSON)
+
+	meta := LibraryMetadata{
+		GeneratorVersion: opts.GeneratorVersion,
+		ProviderSrc:      opts.ProviderSrc,
+		ProviderVersion:  opts.ProviderVersion,
+		TerraformVersion: opts.TerraformVersion,
+		SchemaHash:       schemaHashPrefix + hex.EncodeToString(sum[:]),
+		GeneratedAt:      time.Now().UTC(),
+	}
+	return meta, nil
+}
+
+// header returns the comment block that is prepended to each generated libsonnet file. Note that this intentionally
+// omits the timestamp so that regenerating the library from the same inputs does not change every file.
+func (m LibraryMetadata) header() string {
+	generator := "libgenerator"
+	if m.GeneratorVersion != "" {
+		generator += " " + m.GeneratorVersion
+	}
+	lines := []string{
+		fmt.Sprintf("Code generated by %s. DO NOT EDIT.", generator),
+	}
+
+	if m.ProviderSrc != "" {
+		provider := m.ProviderSrc
+		if m.ProviderVersion != "" {
+			provider += " " + m.ProviderVersion
+		}
+		lines = append(lines, "Provider: "+provider)
+	}
+	if m.TerraformVersion != "" {
+		lines = append(lines, "Terraform: "+m.TerraformVersion)
+	}
+
+	out := ""
+	for _, l := range lines {
+		out += "// " + strings.TrimSpace(l) + "\n"
+	}
+	return out
+}
+
+// writeMetadataToFile writes the given library metadata as JSON to a file.
+func writeMetadataToFile(logger *zap.SugaredLogger, meta LibraryMetadata, fpath string) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fdir := filepath.Dir(fpath)
+	if err := os.MkdirAll(fdir, 0755); err != nil {
+		return err
+	}
+
+	logger.Debugf("Writing library metadata to %s", fpath)
+	return os.WriteFile(fpath, append(data, '\n'), 0644)
+}
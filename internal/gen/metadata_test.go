@@ -0,0 +1,69 @@
+package gen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+)
+
+func TestLibraryMetadataHeader(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	opts := RenderLibraryOpts{
+		ProviderName:     "tfcoremock",
+		Schema:           loadSchema(g, tfcoremockSchemaF),
+		ProviderSrc:      "registry.terraform.io/hashicorp/tfcoremock",
+		ProviderVersion:  "0.1.2",
+		TerraformVersion: "1.3.6",
+		GeneratorVersion: "v0.0.1",
+	}
+	meta, err := newLibraryMetadata(opts)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(meta.SchemaHash).To(HavePrefix(schemaHashPrefix))
+
+	// The schema hash should be stable for the same schema.
+	metaAgain, err := newLibraryMetadata(opts)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(metaAgain.SchemaHash).To(Equal(meta.SchemaHash))
+
+	g.Expect(meta.header()).To(Equal(strings.Join([]string{
+		"// Code generated by libgenerator v0.0.1. DO NOT EDIT.",
+		"// Provider: registry.terraform.io/hashicorp/tfcoremock 0.1.2",
+		"// Terraform: 1.3.6",
+		"",
+	}, "\n")))
+
+	// Unknown versions are omitted from the header.
+	g.Expect(LibraryMetadata{}.header()).To(Equal("// Code generated by libgenerator. DO NOT EDIT.\n"))
+}
+
+func TestWriteMetadataToFile(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+	logger := logging.GetSugaredLoggerForTest()
+
+	meta, err := newLibraryMetadata(RenderLibraryOpts{
+		ProviderName: "tfcoremock",
+		Schema:       loadSchema(g, tfcoremockSchemaF),
+		ProviderSrc:  "registry.terraform.io/hashicorp/tfcoremock",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	fpath := filepath.Join(t.TempDir(), libRootDirName, metadataFileName)
+	g.Expect(writeMetadataToFile(logger, meta, fpath)).To(Succeed())
+
+	data, err := os.ReadFile(fpath)
+	g.Expect(err).NotTo(HaveOccurred())
+	var loaded LibraryMetadata
+	g.Expect(json.Unmarshal(data, &loaded)).To(Succeed())
+	g.Expect(loaded.ProviderSrc).To(Equal(meta.ProviderSrc))
+	g.Expect(loaded.SchemaHash).To(Equal(meta.SchemaHash))
+	g.Expect(loaded.GeneratedAt.Equal(meta.GeneratedAt)).To(BeTrue())
+}
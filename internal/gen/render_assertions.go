@@ -0,0 +1,88 @@
+package gen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	j "github.com/jsonnet-libs/k8s/pkg/builder"
+)
+
+// typeAssertion represents the check for the type of a constructor param.
+type typeAssertion struct {
+	// checks is the list of std functions (e.g., std.isString) that the param must pass at least one of.
+	checks []string
+
+	// desc is the human readable description of the expected type that is shown in the error message.
+	desc string
+}
+
+// withParamAssertions prepends assertions to the given constructor body that check the types of the params for all the
+// attributes and nested blocks of the schema, based on the cty type of each attribute. The error messages name the
+// given object (e.g., the resource type) and the attribute. Note that params of any type are not checked, and null is
+// always allowed as it is treated the same as omitting the param.
+//
+// Since any attribute can be set to a Terraform expression (e.g., a reference to another resource), string values are
+// accepted for all attributes regardless of the type.
+func withParamAssertions(objectName string, schema *tfjson.SchemaBlock, body j.Type) j.Type {
+	asserts := []string{}
+
+	for param, cfg := range getInputAttributes(schema) {
+		assertion, hasAssertion := attrTypeAssertion(cfg.attr)
+		if !hasAssertion {
+			continue
+		}
+		asserts = append(asserts, assertionExpr(objectName, cfg.tfName, param, assertion))
+	}
+
+	for param, cfg := range getNestedBlocks(schema) {
+		assertion := blockTypeAssertion(cfg.block.NestingMode)
+		asserts = append(asserts, assertionExpr(objectName, cfg.tfName, param, assertion))
+	}
+
+	if len(asserts) == 0 {
+		return body
+	}
+	sort.Strings(asserts)
+	return j.Ref("", strings.Join(asserts, "\n")+"\n"+body.String())
+}
+
+// attrTypeAssertion returns the type assertion for the given attribute. Returns false if the attribute can be of any
+// type.
+func attrTypeAssertion(attr *tfjson.SchemaAttribute) (typeAssertion, bool) {
+	switch getAttrType(attr) {
+	case "string":
+		return typeAssertion{checks: []string{"std.isString"}, desc: "a string"}, true
+	case "number":
+		return typeAssertion{checks: []string{"std.isNumber", "std.isString"}, desc: "a number or an expression string"}, true
+	case "bool":
+		return typeAssertion{checks: []string{"std.isBoolean", "std.isString"}, desc: "a bool or an expression string"}, true
+	case "list", "list[obj]":
+		return typeAssertion{checks: []string{"std.isArray", "std.isString"}, desc: "an array or an expression string"}, true
+	case "obj", "map[str, obj]":
+		return typeAssertion{checks: []string{"std.isObject", "std.isString"}, desc: "an object or an expression string"}, true
+	}
+	return typeAssertion{}, false
+}
+
+// blockTypeAssertion returns the type assertion for a nested block with the given nesting mode. Note that Terraform
+// accepts a single object for list and set blocks in the JSON syntax.
+func blockTypeAssertion(nestingMode tfjson.SchemaNestingMode) typeAssertion {
+	if getCollectionType(nestingMode) == IsListOrSet {
+		return typeAssertion{checks: []string{"std.isArray", "std.isObject"}, desc: "an array or an object"}
+	}
+	return typeAssertion{checks: []string{"std.isObject"}, desc: "an object"}
+}
+
+// assertionExpr returns the jsonnet assert expression that checks the given param against the type assertion.
+func assertionExpr(objectName, attrTFName, param string, assertion typeAssertion) string {
+	conds := []string{fmt.Sprintf("%s == null", param)}
+	for _, check := range assertion.checks {
+		conds = append(conds, fmt.Sprintf("%s(%s)", check, param))
+	}
+	return fmt.Sprintf(
+		"assert %s : '%s.%s must be %s, got ' + std.type(%s);",
+		strings.Join(conds, " || "), objectName, attrTFName, assertion.desc, param,
+	)
+}
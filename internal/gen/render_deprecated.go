@@ -0,0 +1,109 @@
+package gen
+
+import (
+	"fmt"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	j "github.com/jsonnet-libs/k8s/pkg/builder"
+)
+
+// deprecationWarning returns the jsonnet expression that evaluates to the given value expression, emitting a warning
+// with std.trace that the given attribute or block is deprecated.
+func deprecationWarning(objectName, attrTFName, valueExpr string) string {
+	msg := fmt.Sprintf("WARNING: %s.%s is deprecated", objectName, attrTFName)
+	return fmt.Sprintf("std.trace(%q, %s)", msg, valueExpr)
+}
+
+// withDeprecationWarnings updates the given object field setters for a constructor so that a warning is emitted with
+// std.trace when a deprecated attribute or block is set (that is, the param is not null).
+func withDeprecationWarnings(objectName string, schema *tfjson.SchemaBlock, fields sortedTypeList) sortedTypeList {
+	deprecated := map[string]string{}
+	for param, cfg := range getInputAttributes(schema) {
+		if cfg.attr.Deprecated {
+			deprecated[cfg.tfName] = param
+		}
+	}
+	for param, cfg := range getNestedBlocks(schema) {
+		if cfg.isDeprecated() {
+			deprecated[cfg.tfName] = param
+		}
+	}
+
+	out := make(sortedTypeList, 0, len(fields))
+	for _, f := range fields {
+		param, isDeprecated := deprecated[f.Name()]
+		if !isDeprecated {
+			out = append(out, f)
+			continue
+		}
+		warned := fmt.Sprintf(
+			"if %s == null then null else %s",
+			param, deprecationWarning(objectName, f.Name(), param),
+		)
+		out = append(out, j.Ref(f.Name(), warned))
+	}
+	return out
+}
+
+// withoutDeprecated returns a copy of the given schema with all the deprecated attributes and blocks removed, including
+// those in nested blocks and nested attribute types.
+func withoutDeprecated(schema *tfjson.SchemaBlock) *tfjson.SchemaBlock {
+	if schema == nil {
+		return nil
+	}
+
+	out := *schema
+	out.Attributes = withoutDeprecatedAttributes(schema.Attributes)
+	out.NestedBlocks = map[string]*tfjson.SchemaBlockType{}
+	for name, cfg := range schema.NestedBlocks {
+		if cfg.Block != nil && cfg.Block.Deprecated {
+			continue
+		}
+		blockCfg := *cfg
+		blockCfg.Block = withoutDeprecated(cfg.Block)
+		out.NestedBlocks[name] = &blockCfg
+	}
+	return &out
+}
+
+func withoutDeprecatedAttributes(attrs map[string]*tfjson.SchemaAttribute) map[string]*tfjson.SchemaAttribute {
+	out := map[string]*tfjson.SchemaAttribute{}
+	for name, cfg := range attrs {
+		if cfg.Deprecated {
+			continue
+		}
+		if cfg.AttributeNestedType != nil {
+			attrCfg := *cfg
+			nested := *cfg.AttributeNestedType
+			nested.Attributes = withoutDeprecatedAttributes(cfg.AttributeNestedType.Attributes)
+			attrCfg.AttributeNestedType = &nested
+			cfg = &attrCfg
+		}
+		out[name] = cfg
+	}
+	return out
+}
+
+// schemaWithoutDeprecated returns a copy of the given provider schema with all the deprecated attributes and blocks of
+// the provider config, resources, and data sources removed.
+func schemaWithoutDeprecated(schema *tfjson.ProviderSchema) *tfjson.ProviderSchema {
+	out := *schema
+	if schema.ConfigSchema != nil {
+		cfg := *schema.ConfigSchema
+		cfg.Block = withoutDeprecated(schema.ConfigSchema.Block)
+		out.ConfigSchema = &cfg
+	}
+	out.ResourceSchemas = schemasWithoutDeprecated(schema.ResourceSchemas)
+	out.DataSourceSchemas = schemasWithoutDeprecated(schema.DataSourceSchemas)
+	return &out
+}
+
+func schemasWithoutDeprecated(schemas map[string]*tfjson.Schema) map[string]*tfjson.Schema {
+	out := map[string]*tfjson.Schema{}
+	for name, s := range schemas {
+		sCopy := *s
+		sCopy.Block = withoutDeprecated(s.Block)
+		out[name] = &sCopy
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package gen
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func deprecatedTestSchema() *tfjson.SchemaBlock {
+	return &tfjson.SchemaBlock{
+		Attributes: map[string]*tfjson.SchemaAttribute{
+			"name":     {AttributeType: cty.String, Required: true},
+			"old_name": {AttributeType: cty.String, Optional: true, Deprecated: true},
+		},
+		NestedBlocks: map[string]*tfjson.SchemaBlockType{
+			"settings": {
+				NestingMode: tfjson.SchemaNestingModeSingle,
+				Block: &tfjson.SchemaBlock{
+					Attributes: map[string]*tfjson.SchemaAttribute{
+						"value":     {AttributeType: cty.String, Optional: true},
+						"old_value": {AttributeType: cty.String, Optional: true, Deprecated: true},
+					},
+				},
+			},
+			"legacy": {
+				NestingMode: tfjson.SchemaNestingModeList,
+				Block: &tfjson.SchemaBlock{
+					Deprecated: true,
+					Attributes: map[string]*tfjson.SchemaAttribute{
+						"value": {AttributeType: cty.String, Optional: true},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestRenderResourceDeprecatedDocs(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	jt, err := renderResourceOrDataSource("test", "test_resource", IsResource, deprecatedTestSchema(), renderOpts{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	out := jt.String()
+	g.Expect(out).To(ContainSubstring("`old_name` (`string`): **Deprecated**."))
+	g.Expect(out).To(ContainSubstring("`legacy` (`list[obj]`): **Deprecated**."))
+	g.Expect(out).To(ContainSubstring("**Deprecated**: the `old_name` field is marked as deprecated by the provider."))
+	g.Expect(out).To(ContainSubstring("**Deprecated**: the `old_value` field is marked as deprecated by the provider."))
+	g.Expect(out).NotTo(ContainSubstring("std.trace"))
+}
+
+func TestRenderResourceWarnDeprecated(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	jt, err := renderResourceOrDataSource(
+		"test", "test_resource", IsResource, deprecatedTestSchema(), renderOpts{warnDeprecated: true},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	rendered := jt.String()
+	g.Expect(rendered).To(ContainSubstring(`std.trace("WARNING: test_resource.old_name is deprecated", old_name)`))
+	g.Expect(rendered).To(ContainSubstring(`std.trace("WARNING: test_resource.legacy is deprecated", value)`))
+	g.Expect(rendered).To(ContainSubstring(`std.trace("WARNING: test.resource.settings.old_value is deprecated", value)`))
+
+	// The warnings should not change the resulting document.
+	out := evalRenderedDoc(g, rendered, `
+local r = import 'resource.libsonnet';
+r.new('foo', name='foo', old_name='bar')
++ r.withSettings('foo', r.settings.new(old_value='baz'))
+`)
+	g.Expect(out).To(MatchJSON(`{
+  "resource": {
+    "test_resource": {
+      "foo": {"name": "foo", "old_name": "bar", "settings": {"old_value": "baz"}}
+    }
+  }
+}`))
+}
+
+func TestSchemaWithoutDeprecated(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := &tfjson.ProviderSchema{
+		ConfigSchema: &tfjson.Schema{Block: deprecatedTestSchema()},
+		ResourceSchemas: map[string]*tfjson.Schema{
+			"test_resource": {Block: deprecatedTestSchema()},
+		},
+	}
+	out := schemaWithoutDeprecated(schema)
+
+	for _, block := range []*tfjson.SchemaBlock{out.ConfigSchema.Block, out.ResourceSchemas["test_resource"].Block} {
+		g.Expect(block.Attributes).To(HaveKey("name"))
+		g.Expect(block.Attributes).NotTo(HaveKey("old_name"))
+		g.Expect(block.NestedBlocks).NotTo(HaveKey("legacy"))
+		g.Expect(block.NestedBlocks["settings"].Block.Attributes).To(HaveKey("value"))
+		g.Expect(block.NestedBlocks["settings"].Block.Attributes).NotTo(HaveKey("old_value"))
+	}
+
+	// The original schema should be left untouched.
+	g.Expect(schema.ResourceSchemas["test_resource"].Block.Attributes).To(HaveKey("old_name"))
+	g.Expect(schema.ResourceSchemas["test_resource"].Block.NestedBlocks).To(HaveKey("legacy"))
+}
@@ -1,8 +1,10 @@
 package gen
 
 import (
+	"fmt"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	j "github.com/jsonnet-libs/k8s/pkg/builder"
 	d "github.com/jsonnet-libs/k8s/pkg/builder/docsonnet"
@@ -15,21 +17,124 @@ const (
 )
 
 type indexImports struct {
-	providerName string
-	resources    []string
-	dataSources  []string
+	providerName   string
+	providerDocURL string
+	resources      []string
+	dataSources    []string
+
+	// skipDataSources is set when the data sources are omitted from the library, in which case the data source index is
+	// not rendered or imported.
+	skipDataSources bool
+
+	// groupSegment is the position (starting from 1) of the segment of the resource and data source names to group the
+	// objects by, where segments are separated by _. When set, the objects of each group are imported in a separate
+	// index file that is nested under the root index. This is 0 when grouping is disabled.
+	groupSegment int
 }
 
-func renderIndex(idx indexImports) (j.Doc, error) {
+// objectGroup returns the name of the group that the given resource or data source (without the provider prefix) is
+// placed in when grouping by the given segment (see indexImports.groupSegment). This returns an empty string if the
+// object is not grouped, which is the case when grouping is disabled, or when the name has fewer segments than the
+// group segment (e.g., when grouping by the second segment, instance is not grouped, while kubernetes_cluster is grouped
+// in cluster). Names that end at the group segment are placed in the group of the same name, so that aws_vpc is grouped
+// in vpc alongside aws_vpc_endpoint when grouping by the first segment.
+func objectGroup(objectName string, groupSegment int) string {
+	if groupSegment <= 0 {
+		return ""
+	}
+	segments := strings.Split(objectName, "_")
+	if len(segments) < groupSegment {
+		return ""
+	}
+	return segments[groupSegment-1]
+}
+
+// groupObjects buckets the given resources or data sources (without the provider prefix) by group (see objectGroup),
+// returning the objects that are not grouped separately. An object that is too short to be grouped, but that has the
+// same name as a group, is placed in that group so that it does not clash with the group in the index.
+func groupObjects(objectNames []string, groupSegment int) ([]string, map[string][]string) {
+	maybeUngrouped := []string{}
+	groups := map[string][]string{}
+	for _, name := range objectNames {
+		group := objectGroup(name, groupSegment)
+		if group == "" {
+			maybeUngrouped = append(maybeUngrouped, name)
+			continue
+		}
+		groups[group] = append(groups[group], name)
+	}
+
+	ungrouped := []string{}
+	for _, name := range maybeUngrouped {
+		if _, isGroup := groups[name]; isGroup {
+			groups[name] = append(groups[name], name)
+			continue
+		}
+		ungrouped = append(ungrouped, name)
+	}
+	return ungrouped, groups
+}
+
+// objectGroups returns the group of each of the given resources or data sources (without the provider prefix), keyed
+// by the object name. Objects that are not grouped are omitted.
+func objectGroups(objectNames []string, groupSegment int) map[string]string {
+	_, groups := groupObjects(objectNames, groupSegment)
+	out := map[string]string{}
+	for group, names := range groups {
+		for _, name := range names {
+			out[name] = group
+		}
+	}
+	return out
+}
+
+// objectLibsonnetPath returns the path to the libsonnet file of the given resource or data source (without the provider
+// prefix) in the given group, relative to the resources or data sources folder.
+func objectLibsonnetPath(objectName, group string) string {
+	return filepath.Join(group, nameToLibsonnetName("", objectName))
+}
+
+// indexFields returns the import fields for the given resources or data sources (without the provider prefix) in the
+// given folder. When grouping is enabled, the grouped objects are imported through the index of the group instead. The
+// group field names must not clash with the names of ungrouped objects or the given reserved field names.
+func indexFields(objectNames []string, dir string, groupSegment int, reserved ...string) (sortedTypeList, error) {
+	ungrouped, groups := groupObjects(objectNames, groupSegment)
+
+	taken := map[string]bool{}
+	for _, name := range reserved {
+		taken[name] = true
+	}
+
 	fields := sortedTypeList{}
-	for _, r := range idx.resources {
-		libsonnet := nameToLibsonnetName(idx.providerName, r)
+	for _, name := range ungrouped {
+		taken[name] = true
+		fields = append(
+			fields,
+			j.Import(name, filepath.Join(".", dir, nameToLibsonnetName("", name))),
+		)
+	}
+	for group := range groups {
+		if taken[group] {
+			return nil, fmt.Errorf("group %s clashes with an existing field of the %s index", group, dir)
+		}
 		fields = append(
 			fields,
-			j.Import(r, filepath.Join(".", libResourcesDirName, libsonnet)),
+			j.Import(group, filepath.Join(".", dir, group, mainLibsonnetName)),
 		)
 	}
 	sort.Sort(fields)
+	return fields, nil
+}
+
+func renderIndex(idx indexImports) (j.Doc, error) {
+	reserved := []string{"provider"}
+	if !idx.skipDataSources {
+		reserved = append(reserved, libDataSourcesDirName)
+	}
+	fields, err := indexFields(idx.resources, libResourcesDirName, idx.groupSegment, reserved...)
+	if err != nil {
+		return j.Doc{}, err
+	}
 
 	// Prepend the provider field after the resources are added and sorted so that it is always the first item in the
 	// object.
@@ -40,13 +145,15 @@ func renderIndex(idx indexImports) (j.Doc, error) {
 	)
 
 	// Import the data source index, namespaced under the data key.
-	fields = append(
-		fields,
-		j.Import("data", filepath.Join(".", libDataSourcesDirName, "main.libsonnet")),
-	)
+	if !idx.skipDataSources {
+		fields = append(
+			fields,
+			j.Import("data", filepath.Join(".", libDataSourcesDirName, "main.libsonnet")),
+		)
+	}
 
 	// Generate pkg docs and prepend to the fields list so that it is the first field.
-	docstr, err := rootDocString(idx.providerName, "TODO")
+	docstr, err := rootDocString(idx.providerName, idx.providerDocURL)
 	if err != nil {
 		return j.Doc{}, err
 	}
@@ -60,16 +167,11 @@ func renderIndex(idx indexImports) (j.Doc, error) {
 	}, nil
 }
 
-func renderDataIndex(idx indexImports) j.Doc {
-	fields := sortedTypeList{}
-	for _, data := range idx.dataSources {
-		libsonnet := nameToLibsonnetName(idx.providerName, data)
-		fields = append(
-			fields,
-			j.Import(data, filepath.Join(".", libsonnet)),
-		)
+func renderDataIndex(idx indexImports) (j.Doc, error) {
+	fields, err := indexFields(idx.dataSources, "", idx.groupSegment)
+	if err != nil {
+		return j.Doc{}, err
 	}
-	sort.Sort(fields)
 
 	// Generate pkg docs and prepend to the fields list so that it is the first field.
 	// TODO
@@ -80,5 +182,33 @@ func renderDataIndex(idx indexImports) j.Doc {
 	return j.Doc{
 		Locals: []j.LocalType{importDocsonnet()},
 		Root:   root,
+	}, nil
+}
+
+// renderGroupIndexes renders the index files for each group of the given resources or data sources (without the
+// provider prefix), keyed by the group name. The index files are expected to be placed in the folder of the group,
+// alongside the libsonnet files of the objects in the group.
+func renderGroupIndexes(objectNames []string, groupSegment int) map[string]j.Doc {
+	_, groups := groupObjects(objectNames, groupSegment)
+
+	out := map[string]j.Doc{}
+	for group, names := range groups {
+		fields := sortedTypeList{}
+		for _, name := range names {
+			fields = append(
+				fields,
+				j.Import(name, filepath.Join(".", nameToLibsonnetName("", name))),
+			)
+		}
+		sort.Sort(fields)
+
+		doc := d.Pkg(group, "", "")
+		fields = append([]j.Type{doc}, fields...)
+
+		out[group] = j.Doc{
+			Locals: []j.LocalType{importDocsonnet()},
+			Root:   j.Object("", fields...),
+		}
 	}
+	return out
 }
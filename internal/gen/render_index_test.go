@@ -0,0 +1,226 @@
+package gen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-jsonnet"
+
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+)
+
+func TestObjectGroup(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		objectName   string
+		groupSegment int
+		expected     string
+	}{
+		{"s3_bucket", 0, ""},
+		{"s3_bucket", 1, "s3"},
+		{"s3_bucket_policy", 1, "s3"},
+		{"instance", 1, "instance"},
+		{"instance", 2, ""},
+		{"kubernetes_cluster_node_pool", 2, "cluster"},
+		{"kubernetes_cluster", 2, "cluster"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.objectName, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+			g.Expect(objectGroup(tc.objectName, tc.groupSegment)).To(Equal(tc.expected))
+		})
+	}
+}
+
+func TestRenderIndexGroupClash(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	_, err := renderIndex(indexImports{
+		providerName: "google",
+		resources:    []string{"data_catalog_entry", "compute_instance"},
+		groupSegment: 1,
+	})
+	g.Expect(err).To(MatchError(ContainSubstring("group data clashes")))
+
+	// The data group is allowed when the data sources are skipped, since the data index is not imported.
+	_, err = renderIndex(indexImports{
+		providerName:    "google",
+		resources:       []string{"data_catalog_entry", "compute_instance"},
+		groupSegment:    1,
+		skipDataSources: true,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestGroupObjects(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name              string
+		objectNames       []string
+		groupSegment      int
+		expectedUngrouped []string
+		expectedGroups    map[string][]string
+	}{
+		{
+			"disabled",
+			[]string{"vpc", "vpc_endpoint"},
+			0,
+			[]string{"vpc", "vpc_endpoint"},
+			map[string][]string{},
+		},
+		{
+			"name_ends_at_group_segment",
+			[]string{"vpc", "vpc_endpoint", "lb", "lb_listener", "instance"},
+			1,
+			[]string{},
+			map[string][]string{
+				"vpc":      {"vpc", "vpc_endpoint"},
+				"lb":       {"lb", "lb_listener"},
+				"instance": {"instance"},
+			},
+		},
+		{
+			"short_name_matches_group",
+			[]string{"instance", "compute_instance", "network"},
+			2,
+			[]string{"network"},
+			map[string][]string{
+				"instance": {"compute_instance", "instance"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			ungrouped, groups := groupObjects(tc.objectNames, tc.groupSegment)
+			g.Expect(ungrouped).To(Equal(tc.expectedUngrouped))
+			g.Expect(groups).To(Equal(tc.expectedGroups))
+
+			// The index should never clash with the groups of the objects.
+			_, err := renderIndex(indexImports{
+				providerName: "aws",
+				resources:    tc.objectNames,
+				groupSegment: tc.groupSegment,
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+		})
+	}
+}
+
+func TestRenderLibraryGrouped(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		extraObjects  []string
+		expectedFiles []string
+		snippet       string
+		expected      string
+	}{
+		{
+			"tfcoremock",
+			nil,
+			[]string{
+				"resources/simple/main.libsonnet",
+				"resources/simple/simple_resource.libsonnet",
+				"resources/complex/main.libsonnet",
+				"resources/complex/complex_resource.libsonnet",
+				"data/simple/main.libsonnet",
+				"data/simple/simple_resource.libsonnet",
+			},
+			`
+lib.simple.simple_resource.new('foo', string='bar')
++ lib.data.simple.simple_resource.new('foo')
+`,
+			`{
+  "resource": {"tfcoremock_simple_resource": {"foo": {"string": "bar"}}},
+  "data": {"tfcoremock_simple_resource": {"foo": {}}}
+}`,
+		},
+		{
+			// tfcoremock_simple ends at the group segment, and would clash with the simple group if it was not grouped.
+			"name_ends_at_group_segment",
+			[]string{"tfcoremock_simple"},
+			[]string{
+				"resources/simple/main.libsonnet",
+				"resources/simple/simple.libsonnet",
+				"resources/simple/simple_resource.libsonnet",
+				"data/simple/main.libsonnet",
+				"data/simple/simple.libsonnet",
+				"data/simple/simple_resource.libsonnet",
+			},
+			`
+lib.simple.simple.new('foo', string='bar')
++ lib.simple.simple_resource.new('foo', string='baz')
++ lib.data.simple.simple.new('foo')
+`,
+			`{
+  "resource": {
+    "tfcoremock_simple": {"foo": {"string": "bar"}},
+    "tfcoremock_simple_resource": {"foo": {"string": "baz"}}
+  },
+  "data": {"tfcoremock_simple": {"foo": {}}}
+}`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+			logger := logging.GetSugaredLoggerForTest()
+
+			schema := loadSchema(g, tfcoremockSchemaF)
+			for _, name := range tc.extraObjects {
+				schema.ResourceSchemas[name] = schema.ResourceSchemas["tfcoremock_simple_resource"]
+				schema.DataSourceSchemas[name] = schema.DataSourceSchemas["tfcoremock_simple_resource"]
+			}
+
+			workDir := t.TempDir()
+			libDir := filepath.Join(workDir, "tfcoremock")
+			opts := RenderLibraryOpts{
+				ProviderName:   "tfcoremock",
+				Schema:         schema,
+				GroupBySegment: 1,
+			}
+			g.Expect(RenderLibrary(logger, context.Background(), libDir, opts)).To(Succeed())
+
+			genDir := filepath.Join(libDir, libRootDirName)
+			for _, f := range tc.expectedFiles {
+				g.Expect(filepath.Join(genDir, f)).To(BeARegularFile())
+			}
+
+			// Evaluate the library with stand-ins for the core and docsonnet libraries to make sure the group indexes
+			// resolve.
+			vendorDir := filepath.Join(workDir, "vendor")
+			for fpath, contents := range map[string]string{
+				coreImportPath:      stubCoreLibsonnet,
+				docsonnetImportPath: stubDocsonnetLibsonnet,
+			} {
+				fullPath := filepath.Join(vendorDir, fpath)
+				g.Expect(os.MkdirAll(filepath.Dir(fullPath), 0755)).To(Succeed())
+				g.Expect(os.WriteFile(fullPath, []byte(contents), 0644)).To(Succeed())
+			}
+			vm := jsonnet.MakeVM()
+			vm.Importer(&jsonnet.FileImporter{JPaths: []string{vendorDir, workDir}})
+			out, err := vm.EvaluateAnonymousSnippet(
+				"test.jsonnet",
+				"local lib = import 'tfcoremock/main.libsonnet';"+tc.snippet,
+			)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(out).To(MatchJSON(tc.expected))
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package gen
 
 import (
+	"context"
+	"os"
 	"path/filepath"
 
 	tfjson "github.com/hashicorp/terraform-json"
@@ -12,6 +14,63 @@ type RenderLibraryOpts struct {
 	ProviderName   string
 	ResourcePrefix string
 	Schema         *tfjson.ProviderSchema
+
+	// The following are recorded in the library metadata, and are all optional.
+	ProviderSrc      string
+	ProviderVersion  string
+	TerraformVersion string
+	GeneratorVersion string
+
+	// ProviderDocURL overrides the URL to the provider docs that is linked from the generated docs. When empty, this is
+	// derived from ProviderSrc and ProviderVersion if the provider is hosted on the public Terraform or OpenTofu
+	// registry.
+	ProviderDocURL string
+
+	// WithAssertions adds assertions to the generated constructors that check the types of the passed in attributes and
+	// blocks when the Jsonnet code is evaluated.
+	WithAssertions bool
+
+	// WarnDeprecated makes the generated functions emit a warning with std.trace when a deprecated attribute or block
+	// is set.
+	WarnDeprecated bool
+
+	// SkipDeprecated omits the deprecated attributes and blocks from the generated library entirely.
+	SkipDeprecated bool
+
+	// WithSensitiveAttrs adds a sensitiveAttrs function to each provider, resource, and data source that lists the
+	// attributes that are marked as sensitive or write-only.
+	WithSensitiveAttrs bool
+
+	// IncludeResources and ExcludeResources are the patterns for filtering the resources to render, matched against the
+	// full resource type name (e.g., aws_s3_bucket). Patterns wrapped in slashes (e.g., /^aws_s3_.*$/) are regular
+	// expressions, and all other patterns are globs (e.g., aws_s3_*). When IncludeResources is empty, all resources that
+	// are not excluded are rendered.
+	IncludeResources []string
+	ExcludeResources []string
+
+	// IncludeDataSources and ExcludeDataSources are the patterns for filtering the data sources to render. These follow
+	// the same rules as IncludeResources and ExcludeResources.
+	IncludeDataSources []string
+	ExcludeDataSources []string
+
+	// SkipDataSources omits all the data sources from the generated library, including the data source index.
+	SkipDataSources bool
+
+	// GroupBySegment enables grouping the resources and data sources by the segment of the name (without the provider
+	// prefix) at the given position, starting from 1. For example, grouping by the first segment places aws_s3_bucket
+	// in the s3 group. Each group is imported through its own index file that is nested under the root index, so that
+	// only the groups that are used are imported. Names that end at the group segment are placed in the group of the
+	// same name (e.g., aws_vpc is available as vpc.vpc), and names with fewer segments are not grouped. Grouping is
+	// disabled when this is 0.
+	GroupBySegment int
+
+	// RenderJobs is the maximum number of resources and data sources that are rendered concurrently. When 0, this
+	// defaults to the number of CPUs that are available.
+	RenderJobs int
+
+	// WithDocs renders Markdown reference docs for the provider, resources, and data sources into the docs folder of
+	// the library.
+	WithDocs bool
 }
 
 // RenderLibrary renders a full provider schema as a libsonnet library. The libsonnet library has the following
@@ -24,16 +83,59 @@ type RenderLibraryOpts struct {
 // resource block.
 // - `_gen/data_DATASRC.libsonnet`: A data source object file containing definitions for constructing the given
 // data source block.
+// - `_gen/metadata.json`: Metadata recording the generator, provider, and Terraform versions that were used to
+// generate the library.
+// - `docs`: Folder containing the Markdown reference docs for the library. This is only rendered when WithDocs is set.
+//
+// Each generated libsonnet file is stamped with a header comment containing the generator and provider versions.
+//
+// The resource and data source files are rendered concurrently, with up to opts.RenderJobs files rendered at a time.
+//
+// The library is first rendered into a staging directory within outDir, and only moved into place once all the files
+// are rendered successfully. This ensures that a failed or cancelled render (e.g., through ctx) never leaves behind a
+// partially written library.
 func RenderLibrary(
 	logger *zap.SugaredLogger,
+	ctx context.Context,
+	outDir string,
+	opts RenderLibraryOpts,
+) error {
+	stagingDir, err := newStagingDir(outDir)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingDir)
+	logger.Debugf("Using staging directory %s", stagingDir)
+
+	if err := renderLibraryToDir(logger, ctx, stagingDir, opts); err != nil {
+		return err
+	}
+
+	logger.Debugf("Moving rendered library into %s", outDir)
+	return commitStagingDir(stagingDir, outDir)
+}
+
+// renderLibraryToDir renders the library files into the given directory. See RenderLibrary for the folder structure.
+func renderLibraryToDir(
+	logger *zap.SugaredLogger,
+	ctx context.Context,
 	outDir string,
 	opts RenderLibraryOpts,
 ) error {
 	libraryFPath := filepath.Join(outDir, libRootDirName)
 	resourcesFPath := filepath.Join(libraryFPath, libResourcesDirName)
 	dataSourcesFPath := filepath.Join(libraryFPath, libDataSourcesDirName)
+	docsFPath := filepath.Join(outDir, libDocsDirName)
+	docsDataFPath := filepath.Join(docsFPath, libDataSourcesDirName)
+	docURL, err := resolveProviderDocURL(logger, opts)
+	if err != nil {
+		return err
+	}
 	idx := indexImports{
-		providerName: opts.ProviderName,
+		providerName:    opts.ProviderName,
+		providerDocURL:  docURL,
+		skipDataSources: opts.SkipDataSources,
+		groupSegment:    opts.GroupBySegment,
 	}
 
 	resrcPrefix := opts.ProviderName
@@ -41,8 +143,28 @@ func RenderLibrary(
 		resrcPrefix = opts.ResourcePrefix
 	}
 
+	meta, err := newLibraryMetadata(opts)
+	if err != nil {
+		return err
+	}
+	header := meta.header()
+	rOpts := renderOpts{
+		withAssertions: opts.WithAssertions,
+		warnDeprecated: opts.WarnDeprecated,
+
+		withSensitiveAttrs: opts.WithSensitiveAttrs,
+		providerDocURL:     docURL,
+	}
+	schema, err := schemaWithFilters(opts.Schema, opts)
+	if err != nil {
+		return err
+	}
+	if opts.SkipDeprecated {
+		schema = schemaWithoutDeprecated(schema)
+	}
+
 	logger.Info("Rendering provider config generator")
-	doc, err := renderProvider(opts.ProviderName, opts.Schema.ConfigSchema.Block)
+	doc, err := renderProvider(opts.ProviderName, schema.ConfigSchema.Block, rOpts)
 	if err != nil {
 		return err
 	}
@@ -51,65 +173,85 @@ func RenderLibrary(
 		libraryFPath,
 		providerNameToLibsonnetName(opts.ProviderName),
 	)
-	if err := writeDocToFile(logger, doc, providerFPath); err != nil {
+	if err := writeDocToFile(logger, doc, header, providerFPath); err != nil {
 		return err
 	}
-
-	// Render the resource libsonnet files
-	for resrcName, resrcSchema := range opts.Schema.ResourceSchemas {
-		logger.Infof("Rendering %s", resrcName)
-
-		idx.resources = append(
-			idx.resources,
-			nameWithoutProvider(resrcPrefix, resrcName),
-		)
-
-		doc, err := renderResourceOrDataSource(
-			opts.ProviderName, resrcName, IsResource, resrcSchema.Block,
-		)
-		if err != nil {
+	if opts.WithDocs {
+		if err := writeMarkdownDocToFile(logger, doc, filepath.Join(docsFPath, docsProviderName)); err != nil {
 			return err
 		}
+	}
 
-		resrcFPath := filepath.Join(
-			resourcesFPath,
-			nameToLibsonnetName(resrcPrefix, resrcName),
-		)
-		if err := writeDocToFile(logger, doc, resrcFPath); err != nil {
+	// Render the resource and data source libsonnet files. The index imports are collected upfront from the sorted
+	// tasks so that the index files are deterministic regardless of the order that the files are rendered in.
+	tasks := getRenderTasks(schema)
+	for _, t := range tasks {
+		objName := nameWithoutProvider(resrcPrefix, t.name)
+		if t.kind == IsDataSource {
+			idx.dataSources = append(idx.dataSources, objName)
+		} else {
+			idx.resources = append(idx.resources, objName)
+		}
+	}
+	if opts.WithDocs {
+		if err := checkMarkdownPaths(idx); err != nil {
 			return err
 		}
 	}
+	resrcGroups := objectGroups(idx.resources, idx.groupSegment)
+	dataSrcGroups := objectGroups(idx.dataSources, idx.groupSegment)
+	renderErr := renderConcurrently(ctx, tasks, opts.RenderJobs, func(t renderTask) error {
+		logger.Infof("Rendering %s", t.name)
 
-	// Render the data source libsonnet files
-	for datasrcName, datasrcSchema := range opts.Schema.DataSourceSchemas {
-		logger.Infof("Rendering %s", datasrcName)
-
-		idx.dataSources = append(
-			idx.dataSources,
-			nameWithoutProvider(resrcPrefix, datasrcName),
-		)
+		objName := nameWithoutProvider(resrcPrefix, t.name)
+		objDir, objDocsDir, groups := resourcesFPath, docsFPath, resrcGroups
+		if t.kind == IsDataSource {
+			objDir, objDocsDir, groups = dataSourcesFPath, docsDataFPath, dataSrcGroups
+		}
 
-		doc, err := renderResourceOrDataSource(
-			opts.ProviderName, datasrcName, IsDataSource, datasrcSchema.Block,
-		)
+		// Each task gets its own copy of the render options, so that the docs use the path to the object in its group.
+		objOpts := rOpts
+		objOpts.group = groups[objName]
+		doc, err := renderResourceOrDataSource(opts.ProviderName, t.name, t.kind, t.schema, objOpts)
 		if err != nil {
 			return err
 		}
 
-		datasrcFPath := filepath.Join(
-			dataSourcesFPath,
-			nameToLibsonnetName(resrcPrefix, datasrcName),
-		)
-		if err := writeDocToFile(logger, doc, datasrcFPath); err != nil {
+		objFPath := filepath.Join(objDir, objectLibsonnetPath(objName, objOpts.group))
+		if err := writeDocToFile(logger, doc, header, objFPath); err != nil {
 			return err
 		}
+		if opts.WithDocs {
+			mdFPath := filepath.Join(objDocsDir, objectMarkdownPath(objName, objOpts.group))
+			if err := writeMarkdownDocToFile(logger, doc, mdFPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if renderErr != nil {
+		return renderErr
 	}
 
 	// Render the _gen index file
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	logger.Info("Rendering index files")
-	dataIdx := renderDataIndex(idx)
-	dataIdxFPath := filepath.Join(dataSourcesFPath, mainLibsonnetName)
-	if err := writeDocToFile(logger, &dataIdx, dataIdxFPath); err != nil {
+	if !idx.skipDataSources {
+		dataIdx, err := renderDataIndex(idx)
+		if err != nil {
+			return err
+		}
+		dataIdxFPath := filepath.Join(dataSourcesFPath, mainLibsonnetName)
+		if err := writeDocToFile(logger, &dataIdx, header, dataIdxFPath); err != nil {
+			return err
+		}
+		if err := writeGroupIndexes(logger, idx.dataSources, idx.groupSegment, header, dataSourcesFPath); err != nil {
+			return err
+		}
+	}
+	if err := writeGroupIndexes(logger, idx.resources, idx.groupSegment, header, resourcesFPath); err != nil {
 		return err
 	}
 
@@ -118,17 +260,64 @@ func RenderLibrary(
 		return err
 	}
 	genIdxFPath := filepath.Join(libraryFPath, mainLibsonnetName)
-	if err := writeDocToFile(logger, &genIdx, genIdxFPath); err != nil {
+	if err := writeDocToFile(logger, &genIdx, header, genIdxFPath); err != nil {
 		return err
 	}
 
+	if opts.WithDocs {
+		logger.Info("Rendering Markdown index files")
+		if err := writeMarkdownIndexes(idx, docsFPath); err != nil {
+			return err
+		}
+	}
+
 	// Render the main index file
 	mainImp := j.Import("", filepath.Join(".", "_gen", mainLibsonnetName))
 	mainIdx := j.Doc{Root: mainImp}
 	mainIdxFPath := filepath.Join(outDir, mainLibsonnetName)
-	if err := writeDocToFile(logger, &mainIdx, mainIdxFPath); err != nil {
+	if err := writeDocToFile(logger, &mainIdx, header, mainIdxFPath); err != nil {
 		return err
 	}
 
+	// Render the metadata file
+	metaFPath := filepath.Join(libraryFPath, metadataFileName)
+	if err := writeMetadataToFile(logger, meta, metaFPath); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeGroupIndexes writes the index file for each group of the given resources or data sources (without the provider
+// prefix) into the folder of the group within dir. This is a no-op when grouping is disabled.
+func writeGroupIndexes(logger *zap.SugaredLogger, objectNames []string, groupSegment int, header, dir string) error {
+	for group, doc := range renderGroupIndexes(objectNames, groupSegment) {
+		doc := doc
+		fpath := filepath.Join(dir, group, mainLibsonnetName)
+		if err := writeDocToFile(logger, &doc, header, fpath); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// resolveProviderDocURL returns the URL to the provider docs to link to from the generated docs. This logs a warning
+// when the URL can not be derived from the provider source, since the generated docs will then have no links to the
+// registry docs.
+func resolveProviderDocURL(logger *zap.SugaredLogger, opts RenderLibraryOpts) (string, error) {
+	if opts.ProviderDocURL != "" {
+		return opts.ProviderDocURL, nil
+	}
+
+	docURL, err := providerDocURL(opts.ProviderSrc, opts.ProviderVersion)
+	if err != nil {
+		return "", err
+	}
+	if docURL == "" && opts.ProviderSrc != "" {
+		logger.Warnf(
+			"Could not derive the registry docs URL for provider %s, so the generated docs will not link to the provider docs",
+			opts.ProviderSrc,
+		)
+	}
+	return docURL, nil
+}
@@ -39,7 +39,7 @@ func TestRenderLibrary(t *testing.T) {
 		ProviderName: "tfcoremock",
 		Schema:       schema,
 	}
-	g.Expect(RenderLibrary(logger, libDir, opts)).To(Succeed())
+	g.Expect(RenderLibrary(logger, context.Background(), libDir, opts)).To(Succeed())
 
 	testCases, err := os.ReadDir(renderLibraryTestCasesDir)
 	g.Expect(err).NotTo(HaveOccurred())
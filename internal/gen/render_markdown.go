@@ -0,0 +1,298 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	_ "embed"
+
+	"github.com/google/go-jsonnet"
+	j "github.com/jsonnet-libs/k8s/pkg/builder"
+	"go.uber.org/zap"
+)
+
+const (
+	libDocsDirName     = "docs"
+	docsIndexName      = "README.md"
+	docsDataIndexName  = "index.md"
+	docsProviderName   = "provider.md"
+	markdownExtension  = ".md"
+	markdownLibImport  = "lib.libsonnet"
+	markdownExtractLib = "extract.libsonnet"
+)
+
+var (
+	//go:embed mdtmpls/docutil.libsonnet
+	markdownDocUtilContents string
+
+	//go:embed mdtmpls/extract.libsonnet
+	markdownExtractContents string
+
+	//go:embed mdtmpls/package.md.tmpl
+	markdownPackageTmplContents string
+	markdownPackageTmpl         = template.Must(
+		template.New("package").Parse(markdownPackageTmplContents),
+	)
+
+	//go:embed mdtmpls/index.md.tmpl
+	markdownIndexTmplContents string
+	markdownIndexTmpl         = template.Must(
+		template.New("index").Parse(markdownIndexTmplContents),
+	)
+)
+
+// markdownPackage represents the docsonnet metadata of a generated libsonnet file (provider, resource, or data source),
+// as extracted by mdtmpls/extract.libsonnet.
+type markdownPackage struct {
+	Name    string           `json:"name"`
+	Help    string           `json:"help"`
+	Fns     []markdownFn     `json:"fns"`
+	Objects []markdownObject `json:"objects"`
+}
+
+// markdownObject represents a nested object (e.g., for a nested block) in the generated library.
+type markdownObject struct {
+	Path string       `json:"path"`
+	Fns  []markdownFn `json:"fns"`
+}
+
+// markdownFn represents a function in the generated library. Path is the full path to the function relative to the
+// package, including the nested objects.
+type markdownFn struct {
+	Name string        `json:"name"`
+	Path string        `json:"path"`
+	Help string        `json:"help"`
+	Args []markdownArg `json:"args"`
+}
+
+type markdownArg struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	HasDefault bool            `json:"hasDefault"`
+	Default    json.RawMessage `json:"default"`
+}
+
+// Signature returns the function signature, in the same format as the docsonnet rendered docs.
+func (fn markdownFn) Signature() string {
+	args := make([]string, 0, len(fn.Args))
+	for _, a := range fn.Args {
+		if a.HasDefault {
+			var dflt bytes.Buffer
+			if err := json.Compact(&dflt, a.Default); err != nil {
+				dflt.Write(a.Default)
+			}
+			args = append(args, fmt.Sprintf("%s=%s", a.Name, dflt.String()))
+			continue
+		}
+		args = append(args, a.Name)
+	}
+	return fmt.Sprintf("%s(%s)", fn.Name, strings.Join(args, ", "))
+}
+
+// Anchor returns the anchor to the function docs. These match the anchors that are linked to from the docstrings (see
+// fnDocAnchor).
+func (fn markdownFn) Anchor() string {
+	return "#fn-" + markdownAnchorPath(fn.Path)
+}
+
+// Anchor returns the anchor to the object docs.
+func (obj markdownObject) Anchor() string {
+	return "#obj-" + markdownAnchorPath(obj.Path)
+}
+
+// markdownAnchorPath returns the path to the field for use in the anchor, following the convention of GitHub headings
+// where dots are removed and the name is lower cased.
+func markdownAnchorPath(path string) string {
+	return strings.ToLower(strings.ReplaceAll(path, ".", ""))
+}
+
+type markdownIndex struct {
+	Name        string
+	Help        string
+	Subpackages []markdownIndexEntry
+}
+
+type markdownIndexEntry struct {
+	Name string
+	Path string
+}
+
+// renderMarkdown renders the Markdown reference docs for the given generated libsonnet document. The docsonnet
+// metadata is evaluated with go-jsonnet, using a minimal implementation of the docsonnet library so that the generated
+// library does not need to be vendored.
+func renderMarkdown(doc *j.Doc) (string, error) {
+	vm := jsonnet.MakeVM()
+	vm.Importer(&jsonnet.MemoryImporter{
+		Data: map[string]jsonnet.Contents{
+			docsonnetImportPath: jsonnet.MakeContents(markdownDocUtilContents),
+			coreImportPath:      jsonnet.MakeContents("{}"),
+			markdownExtractLib:  jsonnet.MakeContents(markdownExtractContents),
+			markdownLibImport:   jsonnet.MakeContents(doc.String()),
+		},
+	})
+	out, err := vm.EvaluateAnonymousSnippet(
+		"docs.jsonnet",
+		fmt.Sprintf("(import %q)(import %q)", markdownExtractLib, markdownLibImport),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	var pkg markdownPackage
+	if err := json.Unmarshal([]byte(out), &pkg); err != nil {
+		return "", err
+	}
+	pkg.Help = strings.TrimSpace(pkg.Help)
+	trimFnHelp(pkg.Fns)
+	for _, obj := range pkg.Objects {
+		trimFnHelp(obj.Fns)
+	}
+
+	var md bytes.Buffer
+	err = markdownPackageTmpl.Execute(&md, pkg)
+	return md.String(), err
+}
+
+func trimFnHelp(fns []markdownFn) {
+	for i := range fns {
+		fns[i].Help = strings.TrimSpace(fns[i].Help)
+	}
+}
+
+// renderMarkdownIndex renders the Markdown index page linking to the given sub package pages. The entries are sorted by
+// name to ensure the output is deterministic.
+func renderMarkdownIndex(name, help string, entries []markdownIndexEntry) (string, error) {
+	sorted := append([]markdownIndexEntry{}, entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var md bytes.Buffer
+	err := markdownIndexTmpl.Execute(&md, markdownIndex{
+		Name:        name,
+		Help:        strings.TrimSpace(help),
+		Subpackages: sorted,
+	})
+	return md.String(), err
+}
+
+// writeMarkdownDocToFile renders the Markdown reference docs for the given generated libsonnet document and writes it
+// to the given file path.
+func writeMarkdownDocToFile(logger *zap.SugaredLogger, doc *j.Doc, fpath string) error {
+	md, err := renderMarkdown(doc)
+	if err != nil {
+		logger.Errorf("Error rendering Markdown docs %s", fpath)
+		return err
+	}
+	return writeMarkdownToFile(md, fpath)
+}
+
+func writeMarkdownToFile(md, fpath string) error {
+	if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fpath, []byte(md), 0644)
+}
+
+// objectMarkdownPath returns the path to the Markdown page of the given resource or data source (without the provider
+// prefix) in the given group, relative to the resources or data sources docs folder. This mirrors the folder structure
+// of the library (see objectLibsonnetPath).
+func objectMarkdownPath(objectName, group string) string {
+	return path.Join(group, objectName+markdownExtension)
+}
+
+// objectMarkdownIndexEntries returns the index entries for the pages of the given resources or data sources (without the
+// provider prefix). When grouping is enabled, the entries are named by the path to the object in the library (e.g.,
+// s3.s3_bucket).
+func objectMarkdownIndexEntries(objectNames []string, groupSegment int) []markdownIndexEntry {
+	groups := objectGroups(objectNames, groupSegment)
+	entries := make([]markdownIndexEntry, 0, len(objectNames))
+	for _, name := range objectNames {
+		entryName := name
+		if group := groups[name]; group != "" {
+			entryName = group + "." + name
+		}
+		entries = append(entries, markdownIndexEntry{Name: entryName, Path: objectMarkdownPath(name, groups[name])})
+	}
+	return entries
+}
+
+// checkMarkdownPaths returns an error if the Markdown page of any resource or data source would be written to the same
+// path as another page of the docs. For example, the page of a resource named provider would overwrite the provider
+// page, and the page of a data source named index would overwrite the data sources index page.
+func checkMarkdownPaths(idx indexImports) error {
+	pages := map[string]string{
+		docsIndexName:    "the index",
+		docsProviderName: "the provider",
+	}
+	addPage := func(fpath, owner string) error {
+		if other, clash := pages[fpath]; clash {
+			return fmt.Errorf("the docs page for %s clashes with the docs page for %s at %s", owner, other, fpath)
+		}
+		pages[fpath] = owner
+		return nil
+	}
+
+	resrcGroups := objectGroups(idx.resources, idx.groupSegment)
+	for _, name := range idx.resources {
+		if err := addPage(objectMarkdownPath(name, resrcGroups[name]), "resource "+name); err != nil {
+			return err
+		}
+	}
+	if idx.skipDataSources {
+		return nil
+	}
+
+	if err := addPage(path.Join(libDataSourcesDirName, docsDataIndexName), "the data sources index"); err != nil {
+		return err
+	}
+	dataSrcGroups := objectGroups(idx.dataSources, idx.groupSegment)
+	for _, name := range idx.dataSources {
+		fpath := path.Join(libDataSourcesDirName, objectMarkdownPath(name, dataSrcGroups[name]))
+		if err := addPage(fpath, "data source "+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMarkdownIndexes writes the Markdown index pages for the root of the library and the data sources, which link to
+// the pages of the provider, resources, and data sources.
+func writeMarkdownIndexes(idx indexImports, docsFPath string) error {
+	rootEntries := objectMarkdownIndexEntries(idx.resources, idx.groupSegment)
+	rootEntries = append(rootEntries, markdownIndexEntry{Name: "provider", Path: docsProviderName})
+
+	if !idx.skipDataSources {
+		dataEntries := objectMarkdownIndexEntries(idx.dataSources, idx.groupSegment)
+		dataIdx, err := renderMarkdownIndex(libDataSourcesDirName, "", dataEntries)
+		if err != nil {
+			return err
+		}
+		dataIdxFPath := filepath.Join(docsFPath, libDataSourcesDirName, docsDataIndexName)
+		if err := writeMarkdownToFile(dataIdx, dataIdxFPath); err != nil {
+			return err
+		}
+
+		rootEntries = append(
+			rootEntries,
+			markdownIndexEntry{Name: libDataSourcesDirName, Path: libDataSourcesDirName + "/" + docsDataIndexName},
+		)
+	}
+
+	help, err := rootDocString(idx.providerName, idx.providerDocURL)
+	if err != nil {
+		return err
+	}
+	rootIdx, err := renderMarkdownIndex(idx.providerName, help, rootEntries)
+	if err != nil {
+		return err
+	}
+	return writeMarkdownToFile(rootIdx, filepath.Join(docsFPath, docsIndexName))
+}
@@ -0,0 +1,248 @@
+package gen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-jsonnet"
+	. "github.com/onsi/gomega"
+
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	complexResource := schema.ResourceSchemas["tfcoremock_complex_resource"]
+
+	jt, err := renderResourceOrDataSource(
+		"tfcoremock", "tfcoremock_complex_resource", IsResource, complexResource.Block, renderOpts{},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	md, err := renderMarkdown(jt)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(md).To(HavePrefix("# complex_resource\n"))
+	g.Expect(md).To(ContainSubstring("* [`fn withListBlockMixinAt(resourceLabel, index, value)`](#fn-withlistblockmixinat)"))
+	g.Expect(md).To(ContainSubstring("* [`obj list_block`](#obj-list_block)"))
+	g.Expect(md).To(ContainSubstring("  * [`fn withString(value)`](#fn-list_blockwithstring)"))
+	g.Expect(md).To(ContainSubstring("\n### fn list_block.withString\n"))
+	g.Expect(md).To(ContainSubstring("\n## obj list_block.list_block\n"))
+	g.Expect(md).To(ContainSubstring(
+		"new(resourceLabel, bool=null, float=null, integer=null, list=null, list_block=null, map=null, number=null, " +
+			"object=null, set=null, set_block=null, string=null, _meta={})",
+	))
+}
+
+func TestRenderMarkdownIndex(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	md, err := renderMarkdownIndex("data", "", []markdownIndexEntry{
+		{Name: "simple_resource", Path: "simple_resource.md"},
+		{Name: "complex_resource", Path: "complex_resource.md"},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(md).To(Equal(`# data
+
+## Subpackages
+
+* [complex_resource](complex_resource.md)
+* [simple_resource](simple_resource.md)
+`))
+}
+
+func TestRenderLibraryWithDocs(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+	logger := logging.GetSugaredLoggerForTest()
+
+	libDir := t.TempDir()
+	opts := RenderLibraryOpts{
+		ProviderName: "tfcoremock",
+		Schema:       loadSchema(g, tfcoremockSchemaF),
+		WithDocs:     true,
+	}
+	g.Expect(RenderLibrary(logger, context.Background(), libDir, opts)).To(Succeed())
+
+	docsDir := filepath.Join(libDir, libDocsDirName)
+	for _, f := range []string{
+		"README.md",
+		"provider.md",
+		"complex_resource.md",
+		"simple_resource.md",
+		"data/index.md",
+		"data/complex_resource.md",
+		"data/simple_resource.md",
+	} {
+		g.Expect(filepath.Join(docsDir, f)).To(BeARegularFile())
+	}
+
+	idx, err := os.ReadFile(filepath.Join(docsDir, "README.md"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(idx)).To(ContainSubstring("* [data](data/index.md)"))
+	g.Expect(string(idx)).To(ContainSubstring("* [provider](provider.md)"))
+	g.Expect(string(idx)).To(ContainSubstring("* [simple_resource](simple_resource.md)"))
+}
+
+func TestRenderLibraryGroupedWithDocs(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+	logger := logging.GetSugaredLoggerForTest()
+
+	workDir := t.TempDir()
+	libDir := filepath.Join(workDir, "tfcoremock")
+	opts := RenderLibraryOpts{
+		ProviderName:   "tfcoremock",
+		Schema:         loadSchema(g, tfcoremockSchemaF),
+		WithDocs:       true,
+		GroupBySegment: 1,
+	}
+	g.Expect(RenderLibrary(logger, context.Background(), libDir, opts)).To(Succeed())
+
+	docsDir := filepath.Join(libDir, libDocsDirName)
+	for _, f := range []string{
+		"README.md",
+		"provider.md",
+		"complex/complex_resource.md",
+		"simple/simple_resource.md",
+		"data/index.md",
+		"data/complex/complex_resource.md",
+		"data/simple/simple_resource.md",
+	} {
+		g.Expect(filepath.Join(docsDir, f)).To(BeARegularFile())
+	}
+
+	idx, err := os.ReadFile(filepath.Join(docsDir, "README.md"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(idx)).To(ContainSubstring("* [simple.simple_resource](simple/simple_resource.md)"))
+	dataIdx, err := os.ReadFile(filepath.Join(docsDir, "data", "index.md"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(dataIdx)).To(ContainSubstring("* [simple.simple_resource](simple/simple_resource.md)"))
+
+	// The docs should refer to the objects by the path that they are placed at in the library, both in the libsonnet
+	// docstrings and the Markdown pages.
+	genDir := filepath.Join(libDir, libRootDirName)
+	testCases := []struct {
+		libFPath string
+		mdFPath  string
+		path     string
+	}{
+		{
+			filepath.Join(genDir, "resources", "simple", "simple_resource.libsonnet"),
+			filepath.Join(docsDir, "simple", "simple_resource.md"),
+			"tfcoremock.simple.simple_resource",
+		},
+		{
+			filepath.Join(genDir, "data", "simple", "simple_resource.libsonnet"),
+			filepath.Join(docsDir, "data", "simple", "simple_resource.md"),
+			"tfcoremock.data.simple.simple_resource",
+		},
+	}
+	for _, tc := range testCases {
+		for _, fpath := range []string{tc.libFPath, tc.mdFPath} {
+			contents, err := os.ReadFile(fpath)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(string(contents)).To(ContainSubstring(tc.path + ".new"))
+			g.Expect(string(contents)).To(ContainSubstring(tc.path + ".withString"))
+		}
+	}
+
+	// Make sure the documented paths work when the library is imported.
+	vendorDir := filepath.Join(workDir, "vendor")
+	for fpath, contents := range map[string]string{
+		coreImportPath:      stubCoreLibsonnet,
+		docsonnetImportPath: stubDocsonnetLibsonnet,
+	} {
+		fullPath := filepath.Join(vendorDir, fpath)
+		g.Expect(os.MkdirAll(filepath.Dir(fullPath), 0755)).To(Succeed())
+		g.Expect(os.WriteFile(fullPath, []byte(contents), 0644)).To(Succeed())
+	}
+	vm := jsonnet.MakeVM()
+	vm.Importer(&jsonnet.FileImporter{JPaths: []string{vendorDir, workDir}})
+	snippet := "local tfcoremock = import 'tfcoremock/main.libsonnet';\n"
+	for _, tc := range testCases {
+		snippet += tc.path + ".new('foo') + " + tc.path + ".withString('foo', 'bar') + "
+	}
+	out, err := vm.EvaluateAnonymousSnippet("test.jsonnet", snippet+"{}")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(out).To(MatchJSON(`{
+  "resource": {"tfcoremock_simple_resource": {"foo": {"string": "bar"}}},
+  "data": {"tfcoremock_simple_resource": {"foo": {"string": "bar"}}}
+}`))
+}
+
+func TestCheckMarkdownPaths(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		idx         indexImports
+		expectedErr string
+	}{
+		{
+			"no_clash",
+			indexImports{resources: []string{"simple_resource"}, dataSources: []string{"simple_resource"}},
+			"",
+		},
+		{
+			"resource_named_provider",
+			indexImports{resources: []string{"provider", "simple_resource"}},
+			"the docs page for resource provider clashes with the docs page for the provider at provider.md",
+		},
+		{
+			"data_source_named_index",
+			indexImports{dataSources: []string{"index"}},
+			"the docs page for data source index clashes with the docs page for the data sources index at data/index.md",
+		},
+		{
+			"data_source_named_index_skipped",
+			indexImports{dataSources: []string{"index"}, skipDataSources: true},
+			"",
+		},
+		{
+			// When grouped, the pages are placed in the folder of the group so that they can not clash with the
+			// provider page.
+			"grouped_resource_named_provider",
+			indexImports{resources: []string{"provider"}, dataSources: []string{"index"}, groupSegment: 1},
+			"",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			err := checkMarkdownPaths(tc.idx)
+			if tc.expectedErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(tc.expectedErr))
+			}
+		})
+	}
+}
+
+func TestRenderLibraryWithDocsClash(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+	logger := logging.GetSugaredLoggerForTest()
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	schema.DataSourceSchemas["tfcoremock_index"] = schema.DataSourceSchemas["tfcoremock_simple_resource"]
+
+	libDir := filepath.Join(t.TempDir(), "tfcoremock")
+	opts := RenderLibraryOpts{
+		ProviderName: "tfcoremock",
+		Schema:       schema,
+		WithDocs:     true,
+	}
+	err := RenderLibrary(logger, context.Background(), libDir, opts)
+	g.Expect(err).To(MatchError(ContainSubstring("the docs page for data source index clashes")))
+	g.Expect(filepath.Join(libDir, libDocsDirName)).NotTo(BeADirectory())
+}
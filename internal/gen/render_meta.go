@@ -0,0 +1,107 @@
+package gen
+
+import (
+	j "github.com/jsonnet-libs/k8s/pkg/builder"
+)
+
+// metaArgument represents a Terraform meta-argument that can be set on resource and data source blocks, in addition to
+// the arguments defined by the provider schema.
+type metaArgument struct {
+	tfName  string
+	typ     string
+	collTyp collectionType
+	flavors []withFnFlavor
+
+	// docsURL is the link to the Terraform documentation for the meta-argument.
+	docsURL string
+
+	// resourceOnly is set on meta-arguments that are not supported on data sources.
+	resourceOnly bool
+}
+
+var metaArguments = []metaArgument{
+	{
+		tfName:  "count",
+		typ:     "number",
+		collTyp: IsNotCollection,
+		flavors: []withFnFlavor{IsSetter},
+		docsURL: "https://developer.hashicorp.com/terraform/language/meta-arguments/count",
+	},
+	{
+		tfName:  "for_each",
+		typ:     "any",
+		collTyp: IsNotCollection,
+		flavors: []withFnFlavor{IsSetter},
+		docsURL: "https://developer.hashicorp.com/terraform/language/meta-arguments/for_each",
+	},
+	{
+		tfName:  "depends_on",
+		typ:     "list",
+		collTyp: IsListOrSet,
+		flavors: []withFnFlavor{IsSetter, IsMixin},
+		docsURL: "https://developer.hashicorp.com/terraform/language/meta-arguments/depends_on",
+	},
+	{
+		tfName:  "provider",
+		typ:     "string",
+		collTyp: IsNotCollection,
+		flavors: []withFnFlavor{IsSetter},
+		docsURL: "https://developer.hashicorp.com/terraform/language/meta-arguments/resource-provider",
+	},
+	{
+		tfName:  "lifecycle",
+		typ:     "obj",
+		collTyp: IsMap,
+		flavors: []withFnFlavor{IsSetter, IsMixin},
+		docsURL: "https://developer.hashicorp.com/terraform/language/meta-arguments/lifecycle",
+	},
+	{
+		tfName:       "provisioner",
+		typ:          "list[obj]",
+		collTyp:      IsListOrSet,
+		flavors:      []withFnFlavor{IsSetter, IsMixin, IsMixinAt},
+		docsURL:      "https://developer.hashicorp.com/terraform/language/resources/provisioners/syntax",
+		resourceOnly: true,
+	},
+	{
+		tfName:       "connection",
+		typ:          "obj",
+		collTyp:      IsMap,
+		flavors:      []withFnFlavor{IsSetter, IsMixin},
+		docsURL:      "https://developer.hashicorp.com/terraform/language/resources/provisioners/connection",
+		resourceOnly: true,
+	},
+}
+
+// metaArgWithFns returns the with functions, along with their docsonnet docs, for setting the Terraform meta-arguments
+// that apply to the given resource or data source. Meta-arguments that conflict with an attribute or block in the
+// schema are skipped, so that the generated with functions for the schema take precedence.
+func metaArgWithFns(
+	providerName, typ string,
+	resrcOrDataSrc resourceOrDataSource,
+	group string,
+	schemaNames map[string]bool,
+) ([]j.Type, error) {
+	out := []j.Type{}
+	for _, arg := range metaArguments {
+		if arg.resourceOnly && resrcOrDataSrc != IsResource {
+			continue
+		}
+		if schemaNames[arg.tfName] {
+			continue
+		}
+
+		for _, flavor := range arg.flavors {
+			doc, err := metaArgWithFnDocs(providerName, typ, resrcOrDataSrc, group, arg, flavor)
+			if err != nil {
+				return nil, err
+			}
+			fn, err := withAttributeOrBlockFnForValue(resrcOrDataSrc, typ, arg.tfName, valueArgName, flavor, arg.collTyp)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, *fn, j.Hidden(*doc))
+		}
+	}
+	return out, nil
+}
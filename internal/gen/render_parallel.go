@@ -0,0 +1,96 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// renderTask represents a resource or data source to render into the library.
+type renderTask struct {
+	// name is the full type name of the resource or data source (e.g., aws_s3_bucket).
+	name   string
+	kind   resourceOrDataSource
+	schema *tfjson.SchemaBlock
+}
+
+// getRenderTasks returns the render tasks for all the resources and data sources in the given provider schema. The
+// tasks are sorted (resources first, then data sources, each by name) so that the render order is deterministic when
+// the tasks are run sequentially.
+func getRenderTasks(schema *tfjson.ProviderSchema) []renderTask {
+	tasks := schemaRenderTasks(schema.ResourceSchemas, IsResource)
+	return append(tasks, schemaRenderTasks(schema.DataSourceSchemas, IsDataSource)...)
+}
+
+func schemaRenderTasks(schemas map[string]*tfjson.Schema, kind resourceOrDataSource) []renderTask {
+	tasks := make([]renderTask, 0, len(schemas))
+	for name, s := range schemas {
+		tasks = append(tasks, renderTask{name: name, kind: kind, schema: s.Block})
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].name < tasks[j].name
+	})
+	return tasks
+}
+
+// renderConcurrently calls renderFn for each of the given tasks, running up to jobs tasks concurrently. When jobs is 0,
+// this defaults to the number of CPUs that are available.
+//
+// Once a task fails, the tasks that have not started yet are skipped and the error of the first failing task is
+// returned, annotated with the name of the resource or data source. If ctx is cancelled, the tasks that have not
+// started yet are skipped and the context error is returned.
+func renderConcurrently(
+	ctx context.Context,
+	tasks []renderTask,
+	jobs int,
+	renderFn func(renderTask) error,
+) error {
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+
+	taskCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, jobs)
+	for _, t := range tasks {
+		t := t
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-taskCtx.Done():
+				return
+			}
+			// Both cases of the select may be ready at the same time, so check again that the remaining tasks have not
+			// been cancelled.
+			if taskCtx.Err() != nil {
+				return
+			}
+
+			if err := renderFn(t); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("error rendering %s %s: %w", t.kind, t.name, err)
+					cancel()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,156 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+)
+
+func TestGetRenderTasksSorted(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	tasks := getRenderTasks(loadSchema(g, tfcoremockSchemaF))
+	names := []string{}
+	for _, task := range tasks {
+		names = append(names, fmt.Sprintf("%s %s", task.kind, task.name))
+	}
+	g.Expect(names).To(Equal([]string{
+		"resource tfcoremock_complex_resource",
+		"resource tfcoremock_simple_resource",
+		"data source tfcoremock_complex_resource",
+		"data source tfcoremock_simple_resource",
+	}))
+}
+
+func TestRenderConcurrentlyBounded(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	tasks := []renderTask{}
+	for i := 0; i < 20; i++ {
+		tasks = append(tasks, renderTask{name: fmt.Sprintf("test_resource_%d", i), kind: IsResource})
+	}
+
+	var mu sync.Mutex
+	rendered := map[string]bool{}
+	var running, maxRunning int32
+	err := renderConcurrently(context.Background(), tasks, 3, func(task renderTask) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		rendered[task.name] = true
+		return nil
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rendered).To(HaveLen(len(tasks)))
+	g.Expect(atomic.LoadInt32(&maxRunning)).To(BeNumerically("<=", 3))
+}
+
+func TestRenderConcurrentlyFirstError(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	tasks := []renderTask{}
+	for i := 0; i < 20; i++ {
+		tasks = append(tasks, renderTask{name: fmt.Sprintf("test_resource_%d", i), kind: IsResource})
+	}
+	tasks[5].kind = IsDataSource
+
+	errBroken := errors.New("broken")
+	var calls int32
+	err := renderConcurrently(context.Background(), tasks, 1, func(task renderTask) error {
+		atomic.AddInt32(&calls, 1)
+		if task.name == "test_resource_5" {
+			return errBroken
+		}
+		return nil
+	})
+	g.Expect(err).To(MatchError(errBroken))
+	g.Expect(err).To(MatchError("error rendering data source test_resource_5: broken"))
+
+	// With a single job, the remaining tasks should be skipped once a task fails.
+	g.Expect(atomic.LoadInt32(&calls)).To(BeNumerically("<", len(tasks)))
+}
+
+func TestRenderConcurrentlyCancelled(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := renderConcurrently(ctx, []renderTask{{name: "test_resource", kind: IsResource}}, 1, func(renderTask) error {
+		return errors.New("should not be called")
+	})
+	g.Expect(err).To(MatchError(context.Canceled))
+}
+
+func TestRenderLibraryDeterministic(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+	logger := logging.GetSugaredLoggerForTest()
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	var prevIdx string
+	for i := 0; i < 3; i++ {
+		libDir := t.TempDir()
+		opts := RenderLibraryOpts{
+			ProviderName: "tfcoremock",
+			Schema:       schema,
+			RenderJobs:   4,
+		}
+		g.Expect(RenderLibrary(logger, context.Background(), libDir, opts)).To(Succeed())
+
+		idx, err := os.ReadFile(filepath.Join(libDir, libRootDirName, mainLibsonnetName))
+		g.Expect(err).NotTo(HaveOccurred())
+		dataIdx, err := os.ReadFile(filepath.Join(libDir, libRootDirName, libDataSourcesDirName, mainLibsonnetName))
+		g.Expect(err).NotTo(HaveOccurred())
+		if i > 0 {
+			g.Expect(string(idx) + string(dataIdx)).To(Equal(prevIdx))
+		}
+		prevIdx = string(idx) + string(dataIdx)
+	}
+}
+
+func BenchmarkRenderLibrary(b *testing.B) {
+	g := NewGomegaWithT(b)
+	logger := logging.GetSugaredLoggerForTest()
+	schema := loadSchema(g, tfcoremockSchemaF)
+
+	for _, jobs := range []int{1, 0} {
+		jobs := jobs
+		name := fmt.Sprintf("jobs=%d", jobs)
+		if jobs == 0 {
+			name = "jobs=default"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				opts := RenderLibraryOpts{
+					ProviderName: "tfcoremock",
+					Schema:       schema,
+					RenderJobs:   jobs,
+				}
+				if err := RenderLibrary(logger, context.Background(), b.TempDir(), opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
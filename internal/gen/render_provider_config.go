@@ -1,6 +1,7 @@
 package gen
 
 import (
+	"fmt"
 	"sort"
 
 	tfjson "github.com/hashicorp/terraform-json"
@@ -8,10 +9,17 @@ import (
 	d "github.com/jsonnet-libs/k8s/pkg/builder/docsonnet"
 )
 
+// providerParams are the provider specific params of the provider constructor, in addition to the attributes and blocks
+// of the provider schema:
+// - alias for setting an alias on the provider block
+// - src and version for injecting in required_providers in the resulting document.
+var providerParams = []string{providerAliasArg, "src", "version"}
+
 // renderProvider will render the libsonnet code for constructing a provider block for the given provider. The generated
-// libsonnet code will only consist of the constructors (including for nested blocks), as implementing the mixin
-// functions for the provider is difficult due to providers being a list instead of a map.
-func renderProvider(name string, schema *tfjson.SchemaBlock) (*j.Doc, error) {
+// libsonnet code will consist of the constructors (including for nested blocks), and the with functions for modifying
+// the provider block in an existing document. Since providers are rendered as a list of blocks (one for each alias),
+// the with functions target the provider block by alias, where a null alias targets the default provider block.
+func renderProvider(name string, schema *tfjson.SchemaBlock, opts renderOpts) (*j.Doc, error) {
 	locals := []j.LocalType{
 		importCore(),
 		importDocsonnet(),
@@ -33,27 +41,60 @@ func renderProvider(name string, schema *tfjson.SchemaBlock) (*j.Doc, error) {
 		return nil, err
 	}
 	attrsConstructor, err := attrsConstructor(
-		newAttrsFnName, "", name, IsProvider, schema,
+		newAttrsFnName, "", name, IsProvider, schema, opts,
 	)
 	if err != nil {
 		return nil, err
 	}
 	rootFields = append(rootFields, *attrsConstructorDocs, j.Hidden(*attrsConstructor))
 
-	// Render constructor for nested blocks
-	nestedFields := sortedTypeList{}
+	if opts.withSensitiveAttrs {
+		sensitiveDocs, err := sensitiveAttrsDocs(fmt.Sprintf("%s.provider", name), name, IsProvider)
+		if err != nil {
+			return nil, err
+		}
+		rootFields = append(rootFields, *sensitiveDocs, j.Hidden(sensitiveAttrsFn(schema)))
+	}
+
+	// Add modifier functions for each attribute
+	for _, cfg := range getInputAttributes(schema) {
+		collTyp := IsNotCollection
+		if cfg.attr.AttributeNestedType != nil {
+			collTyp = getCollectionType(cfg.attr.AttributeNestedType.NestingMode)
+		}
+		withFns, err := withFnsForAttributeOrBlock(
+			name, name, IsProvider, "", cfg.tfName, getAttrType(cfg.attr), collTyp, cfg.labels(), opts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rootFields = append(rootFields, withFns...)
+	}
+
+	// Add modifier functions for each block
 	for _, cfg := range getNestedBlocks(schema) {
-		blockObj, err := nestedBlockObject(name, "", cfg)
+		withFns, err := withFnsForAttributeOrBlock(
+			name, name, IsProvider, "", cfg.tfName, getBlockType(cfg.block.NestingMode),
+			getCollectionType(cfg.block.NestingMode), cfg.labels(), opts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rootFields = append(rootFields, withFns...)
+	}
+
+	// Render constructor for nested blocks and nested attribute types
+	for _, cfg := range getNestedObjects(schema) {
+		blockObj, err := nestedBlockObject(name, cfg.tfName, cfg, opts)
 		if err != nil {
 			return nil, err
 		}
-		nestedFields = append(nestedFields, j.Hidden(blockObj))
+		rootFields = append(rootFields, j.Hidden(blockObj))
 	}
-	sort.Sort(nestedFields)
-	rootFields = append(rootFields, nestedFields...)
+	sort.Sort(rootFields)
 
 	// Prepend package docs
-	docstr, err := providerDocString(name, schema.Description)
+	docstr, err := providerDocString(name, schema.Description, opts.providerDocURL)
 	if err != nil {
 		return nil, err
 	}
@@ -69,10 +110,7 @@ func providerConstructor(name string, schema *tfjson.SchemaBlock) (j.FuncType, e
 
 	params := constructorParamList(schema)
 
-	// Add the provider specific args:
-	// - alias for setting an alias on the provider block
-	// - src and version for injecting in required_providers in the resulting document.
-	providerParams := []string{"alias", "src", "version"}
+	// Add the provider specific args.
 	for _, p := range providerParams {
 		params.params = append(params.params, j.Null(p))
 		providerCallArgs = append(providerCallArgs, j.Ref(p, p))
@@ -95,3 +133,37 @@ func providerConstructor(name string, schema *tfjson.SchemaBlock) (j.FuncType, e
 	)
 	return fn, nil
 }
+
+// providerWithAttributeOrBlockFn returns the function implementation for the with function of the given flavor, which
+// returns a mixin to set or update the given attribute or block on the provider block with the given alias in the root
+// terraform document. Note that the provider blocks are stored as a list keyed by the provider name (see
+// tf.withProvider), so this maps over the existing list to only update the provider block with the matching alias.
+// The attribute or block is set to the given jsonnet expression, which is usually the value arg.
+func providerWithAttributeOrBlockFn(
+	providerName, attrTFName, valueExpr string,
+	flavor withFnFlavor,
+	collTyp collectionType,
+) (*j.FuncType, error) {
+	attrRef, err := withFnSetterForValue(attrTFName, valueExpr, flavor, collTyp)
+	if err != nil {
+		return nil, err
+	}
+
+	// The default provider block omits the alias field, so treat a missing alias as null when matching.
+	patchFn := fmt.Sprintf(
+		"function(p) if (if std.objectHas(p, %q) then p.%s else null) == %s then p + %s else p",
+		providerAliasArg, providerAliasArg, providerAliasArg, j.Object("", attrRef).String(),
+	)
+	patched := j.Call(fmt.Sprintf("[%q]", providerName), "std.map", []j.Type{
+		j.Ref("func", patchFn),
+		j.Ref("arr", fmt.Sprintf("super[%q]", providerName)),
+	})
+
+	result := j.Object("",
+		j.Merge(j.Object(providerInjectAttrName, patched)))
+	fn := j.Func(flavor.fnName(attrTFName),
+		j.Args(withFnArgs(providerAliasArg, flavor)...),
+		result,
+	)
+	return &fn, nil
+}
@@ -14,7 +14,7 @@ func TestRenderProvider(t *testing.T) {
 
 	schema := loadSchema(g, tfcoremockSchemaF)
 
-	jt, err := renderProvider("tfcoremock", schema.ConfigSchema.Block)
+	jt, err := renderProvider("tfcoremock", schema.ConfigSchema.Block, renderOpts{})
 	g.Expect(err).NotTo(HaveOccurred())
 
 	out, err := formatter.Format("", jt.String(), formatter.DefaultOptions())
@@ -22,3 +22,30 @@ func TestRenderProvider(t *testing.T) {
 
 	t.Logf(out)
 }
+
+func TestRenderProviderMixins(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+
+	jt, err := renderProvider("tfcoremock", schema.ConfigSchema.Block, renderOpts{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Update the default provider block and an aliased provider block independently.
+	out := evalRenderedDoc(g, jt.String(), `
+local p = import 'resource.libsonnet';
+p.new(use_only_state=true)
++ p.new(alias='foo')
++ p.withUseOnlyState(null, false)
++ p.withDataDirectory('foo', 'data')
+`)
+	g.Expect(out).To(MatchJSON(`{
+  "provider": {
+    "tfcoremock": [
+      {"use_only_state": false},
+      {"alias": "foo", "data_directory": "data"}
+    ]
+  }
+}`))
+}
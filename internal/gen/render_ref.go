@@ -0,0 +1,108 @@
+package gen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	j "github.com/jsonnet-libs/k8s/pkg/builder"
+)
+
+const (
+	refFnName   = "ref"
+	attrArgName = "attr"
+)
+
+// refFn returns the function implementation for the ref function of the resource or data source, which returns the
+// Terraform interpolation string for referencing an attribute of the block with the given label. Unlike the references
+// generated through the `_ref` attribute of the core library, the attribute path is checked against the schema so that
+// typos are caught when the Jsonnet code is evaluated instead of at plan time.
+//
+// The attribute path can refer to any attribute (including computed attributes), nested block, or attributes of nested
+// blocks and nested attribute types, separated by `.`. Elements of lists and maps can be referenced with the index
+// syntax (e.g., `list_block[0].attr`). Attributes with a complex type (e.g., maps or objects) accept arbitrary sub
+// paths, as the schema does not constrain the keys.
+func refFn(typ string, resrcOrDataSrc resourceOrDataSource, schema *tfjson.SchemaBlock) j.FuncType {
+	paths, openPaths := getReferencePaths(schema)
+
+	refPrefix := typ
+	if resrcOrDataSrc == IsDataSource {
+		refPrefix = "data." + typ
+	}
+	labelArg := resrcOrDataSrc.labelArg()
+
+	// NOTE: the path lists are sorted so that they can be looked up with std.setMember.
+	body := fmt.Sprintf(`
+local paths = %s;
+local openPaths = %s;
+local stripIndex(s) = local idx = std.findSubstr('[', s); if std.length(idx) > 0 then std.substr(s, 0, idx[0]) else s;
+local segments = std.map(stripIndex, std.split(%s, '.'));
+local isOpen = std.length([
+  i for i in std.range(1, std.length(segments)) if std.setMember(std.join('.', segments[0:i]), openPaths)
+]) > 0;
+if std.setMember(std.join('.', segments), paths) || isOpen then
+  '${%s.' + %s + '.' + %s + '}'
+else
+  error '%s does not have an attribute ' + %s
+`,
+		quotedList(paths), quotedList(openPaths),
+		attrArgName,
+		refPrefix, labelArg, attrArgName,
+		typ, attrArgName,
+	)
+
+	return j.Func(
+		refFnName,
+		j.Args(j.Required(j.String(labelArg, "")), j.Required(j.String(attrArgName, ""))),
+		j.Ref("", strings.TrimRight(body, "\n")),
+	)
+}
+
+// getReferencePaths returns the sorted list of all the attribute paths that can be referenced on the given schema.
+// Unlike getInputAttributes, this includes computed attributes. The second list contains the paths of attributes with a
+// complex type, which accept arbitrary sub paths.
+func getReferencePaths(schema *tfjson.SchemaBlock) ([]string, []string) {
+	paths := []string{}
+	openPaths := []string{}
+	collectReferencePaths("", schema.Attributes, schema.NestedBlocks, &paths, &openPaths)
+	sort.Strings(paths)
+	sort.Strings(openPaths)
+	return paths, openPaths
+}
+
+func collectReferencePaths(
+	prefix string,
+	attrs map[string]*tfjson.SchemaAttribute,
+	blocks map[string]*tfjson.SchemaBlockType,
+	paths, openPaths *[]string,
+) {
+	for name, cfg := range attrs {
+		path := prefix + name
+		*paths = append(*paths, path)
+
+		switch {
+		case cfg.AttributeNestedType != nil:
+			collectReferencePaths(path+".", cfg.AttributeNestedType.Attributes, nil, paths, openPaths)
+		case !cfg.AttributeType.IsPrimitiveType():
+			*openPaths = append(*openPaths, path)
+		}
+	}
+
+	for name, cfg := range blocks {
+		path := prefix + name
+		*paths = append(*paths, path)
+		if cfg.Block != nil {
+			collectReferencePaths(path+".", cfg.Block.Attributes, cfg.Block.NestedBlocks, paths, openPaths)
+		}
+	}
+}
+
+// quotedList returns the jsonnet representation of the given list of strings.
+func quotedList(items []string) string {
+	quoted := make([]string, 0, len(items))
+	for _, i := range items {
+		quoted = append(quoted, fmt.Sprintf("%q", i))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -5,7 +5,6 @@ import (
 	"sort"
 
 	tfjson "github.com/hashicorp/terraform-json"
-	"github.com/iancoleman/strcase"
 	j "github.com/jsonnet-libs/k8s/pkg/builder"
 	d "github.com/jsonnet-libs/k8s/pkg/builder/docsonnet"
 )
@@ -21,13 +20,26 @@ import (
 //   - A `with{ATTRIBUTE_NAME}` function for every attribute, which will generate a mixin to update the given resource
 //     or data source block in the document. Note that this flavor of the function will require the name so that it
 //     knows which resource or data source to update.
+//   - If the resource or data source has the conventional `timeouts` block, the `withTimeouts` functions will check
+//     that the timeouts are valid operations with duration strings when the Jsonnet code is evaluated.
+//   - A `with{META_ARGUMENT}` function for every Terraform meta-argument (e.g., `count` and `depends_on`) that applies to
+//     the resource or data source, which works the same way as the attribute with functions.
+//   - `sensitiveAttrs`: A function that returns the paths to the attributes that are marked as sensitive. This is only
+//     rendered when opts.withSensitiveAttrs is set.
+//   - `ref`: A function to construct the Terraform reference to an attribute of the resource or data source with the
+//     given label, validating the attribute path (including computed attributes and nested block paths) against the
+//     schema.
 //   - Each nested block will be an object attributed by the block name in the resulting jsonnet document. The nested
 //     block will have its own `new` functions for constructing the nested block object.
 //   - Nested blocks will recursively nest subblocks if the nested blocks have its own nested blocks.
+//   - Similarly, each attribute with a nested attribute type will be an object attributed by the attribute name, with
+//     its own `new` function for constructing the nested object. This applies to all nesting modes, so for a map of
+//     objects, `new` constructs a single object in the map.
 func renderResourceOrDataSource(
 	providerName, typ string,
 	resrcOrDataSrc resourceOrDataSource,
 	schema *tfjson.SchemaBlock,
+	opts renderOpts,
 ) (*j.Doc, error) {
 	locals := []j.LocalType{
 		importCore(),
@@ -35,7 +47,7 @@ func renderResourceOrDataSource(
 	}
 	rootFields := sortedTypeList{}
 
-	constructorDocs, err := constructorDocs(providerName, typ, resrcOrDataSrc, schema)
+	constructorDocs, err := constructorDocs(providerName, typ, resrcOrDataSrc, opts.group, schema)
 	if err != nil {
 		return nil, err
 	}
@@ -46,105 +58,110 @@ func renderResourceOrDataSource(
 	rootFields = append(rootFields, *constructorDocs, j.Hidden(*constructor))
 
 	attrConstructorDocs, err := attrsConstructorDocs(
-		providerName, typ, resrcOrDataSrc, newAttrsFnName, "", schema,
+		providerName, typ, resrcOrDataSrc, opts.group, newAttrsFnName, "", schema,
 	)
 	if err != nil {
 		return nil, err
 	}
 	attrConstructor, err := attrsConstructor(
-		newAttrsFnName, providerName, typ, resrcOrDataSrc, schema,
+		newAttrsFnName, providerName, typ, resrcOrDataSrc, schema, opts,
 	)
 	if err != nil {
 		return nil, err
 	}
 	rootFields = append(rootFields, *attrConstructorDocs, j.Hidden(*attrConstructor))
 
-	// Add modifier functions for each attribute
-	for _, cfg := range getInputAttributes(schema) {
-		bareWithFnDoc, err := withFnDocs(
-			providerName, typ, resrcOrDataSrc, cfg.tfName, getAttrType(cfg.attr), IsNotCollection,
-			false,
-		)
-		if err != nil {
-			return nil, err
-		}
-		bareWithFn, err := withAttributeOrBlockFn(
-			resrcOrDataSrc, providerName, typ, cfg.tfName, false, IsNotCollection,
-		)
+	refFnDocs, err := refDocs(providerName, typ, resrcOrDataSrc, opts.group)
+	if err != nil {
+		return nil, err
+	}
+	rootFields = append(rootFields, *refFnDocs, j.Hidden(refFn(typ, resrcOrDataSrc, schema)))
+
+	if opts.withSensitiveAttrs {
+		objectName := nameWithoutProvider(providerName, typ)
+		fnPrefix := objectFnPrefix(providerName, objectName, resrcOrDataSrc, opts.group)
+		sensitiveDocs, err := sensitiveAttrsDocs(fnPrefix, objectName, resrcOrDataSrc)
 		if err != nil {
 			return nil, err
 		}
-		rootFields = append(rootFields, *bareWithFn, j.Hidden(*bareWithFnDoc))
-
-		if cfg.attr.AttributeNestedType != nil {
-			collTyp := getCollectionType(cfg.attr.AttributeNestedType.NestingMode)
-			mixinWithFnDoc, err := withFnDocs(
-				providerName, typ, resrcOrDataSrc, cfg.tfName, getAttrType(cfg.attr), collTyp,
-				true,
-			)
-			if err != nil {
-				return nil, err
-			}
-			mixinWithFn, err := withAttributeOrBlockFn(
-				resrcOrDataSrc, providerName, typ, cfg.tfName, true, collTyp,
-			)
-			if err != nil {
-				return nil, err
-			}
-			rootFields = append(rootFields, *mixinWithFnDoc, j.Hidden(*mixinWithFn))
-		}
+		rootFields = append(rootFields, *sensitiveDocs, j.Hidden(sensitiveAttrsFn(schema)))
 	}
 
-	// Add modifier functions for each block
-	for block, cfg := range getNestedBlocks(schema) {
-		collTyp := getCollectionType(cfg.block.NestingMode)
-
-		bareWithFnDoc, err := withFnDocs(
-			providerName, typ, resrcOrDataSrc, cfg.tfName, getBlockType(cfg.block.NestingMode), collTyp,
-			false,
-		)
-		if err != nil {
-			return nil, err
+	// Add modifier functions for each attribute
+	for _, cfg := range getInputAttributes(schema) {
+		collTyp := IsNotCollection
+		if cfg.attr.AttributeNestedType != nil {
+			collTyp = getCollectionType(cfg.attr.AttributeNestedType.NestingMode)
 		}
-		bareWithFn, err := withAttributeOrBlockFn(
-			resrcOrDataSrc, providerName, typ, block, false, collTyp,
+		withFns, err := withFnsForAttributeOrBlock(
+			providerName, typ, resrcOrDataSrc, "", cfg.tfName, getAttrType(cfg.attr), collTyp, cfg.labels(), opts,
 		)
 		if err != nil {
 			return nil, err
 		}
-		rootFields = append(rootFields, *bareWithFn, j.Hidden(*bareWithFnDoc))
+		rootFields = append(rootFields, withFns...)
+	}
 
-		mixinWithFnDoc, err := withFnDocs(
-			providerName, typ, resrcOrDataSrc, cfg.tfName, getBlockType(cfg.block.NestingMode), collTyp,
-			true,
-		)
+	// Add modifier functions for each block. The conventional timeouts block gets dedicated with functions that validate
+	// the timeouts.
+	timeoutsBlock := getTimeoutsBlock(schema)
+	if timeoutsBlock != nil {
+		locals = append(locals, validateTimeoutsLocal())
+	}
+	for _, cfg := range getNestedBlocks(schema) {
+		var withFns []j.Type
+		if timeoutsBlock != nil && cfg.tfName == timeoutsBlockName {
+			withFns, err = timeoutsWithFns(providerName, typ, resrcOrDataSrc, opts.group, timeoutsBlock)
+		} else {
+			withFns, err = withFnsForAttributeOrBlock(
+				providerName, typ, resrcOrDataSrc, "", cfg.tfName, getBlockType(cfg.block.NestingMode),
+				getCollectionType(cfg.block.NestingMode), cfg.labels(), opts,
+			)
+		}
 		if err != nil {
 			return nil, err
 		}
-		mixinWithFn, err := withAttributeOrBlockFn(
-			resrcOrDataSrc, providerName, typ, cfg.tfName, true, collTyp,
-		)
+		rootFields = append(rootFields, withFns...)
+
+		objectName := nameWithoutProvider(providerName, typ)
+		providerNameForNested := objectFnPrefix(providerName, objectName, resrcOrDataSrc, opts.group)
+		blockObj, err := nestedBlockObject(providerNameForNested, cfg.tfName, cfg, opts)
 		if err != nil {
 			return nil, err
 		}
-		rootFields = append(rootFields, *mixinWithFn, j.Hidden(*mixinWithFnDoc))
+		rootFields = append(rootFields, j.Hidden(blockObj))
+	}
 
+	// Add modifier functions for each meta-argument
+	schemaNames := map[string]bool{}
+	for name := range schema.Attributes {
+		schemaNames[name] = true
+	}
+	for name := range schema.NestedBlocks {
+		schemaNames[name] = true
+	}
+	metaWithFns, err := metaArgWithFns(providerName, typ, resrcOrDataSrc, opts.group, schemaNames)
+	if err != nil {
+		return nil, err
+	}
+	rootFields = append(rootFields, metaWithFns...)
+
+	// Add constructor objects for each nested attribute type. Note that the with functions for these are already added
+	// with the other attributes.
+	for _, cfg := range getNestedAttributeTypes(schema) {
 		objectName := nameWithoutProvider(providerName, typ)
-		providerNameForNested := fmt.Sprintf(
-			"%s.%s",
-			providerName, objectName,
-		)
-		blockObj, err := nestedBlockObject(providerNameForNested, cfg.tfName, cfg)
+		providerNameForNested := objectFnPrefix(providerName, objectName, resrcOrDataSrc, opts.group)
+		attrObj, err := nestedBlockObject(providerNameForNested, cfg.tfName, cfg, opts)
 		if err != nil {
 			return nil, err
 		}
-		rootFields = append(rootFields, j.Hidden(blockObj))
+		rootFields = append(rootFields, j.Hidden(attrObj))
 	}
 
 	sort.Sort(rootFields)
 
 	// Inject the package docs at the top
-	docstr, err := objectDocString(providerName, typ, resrcOrDataSrc, schema)
+	docstr, err := objectDocString(providerName, typ, resrcOrDataSrc, schema, opts.providerDocURL)
 	if err != nil {
 		return nil, err
 	}
@@ -208,32 +225,113 @@ func attrsConstructor(
 	fnName, providerName, typ string,
 	resrcOrDataSrc resourceOrDataSource,
 	schema *tfjson.SchemaBlock,
+	opts renderOpts,
 ) (*j.FuncType, error) {
 	params := constructorParamList(schema)
 
+	objectName := qualifiedObjectName(providerName, typ, resrcOrDataSrc)
+	if opts.warnDeprecated {
+		params.tfFieldSetters = withDeprecationWarnings(objectName, schema, params.tfFieldSetters)
+	}
+
 	// Prune null attributes so they are omitted from the final json.
 	// Although this is not strictly necessary to do, it makes the rendered terraform json (NOT jsonnet code!) nice and
 	// tidy.
+	var result j.Type = j.Call(
+		"",
+		"std.prune",
+		[]j.Type{j.Object("a", params.tfFieldSetters...)},
+	)
+	if opts.withAssertions {
+		result = withParamAssertions(objectName, schema, result)
+	}
+
 	fn := j.LargeFunc(
 		fnName,
 		j.Args(params.params...),
-		j.Call(
-			"",
-			"std.prune",
-			[]j.Type{j.Object("a", params.tfFieldSetters...)},
-		),
+		result,
 	)
 	return &fn, nil
 }
 
-func withAttributeOrBlockFn(
+// withFnsForAttributeOrBlock returns the with functions, along with their docsonnet docs, for all the flavors that are
+// supported on the given attribute or block. When resrcOrDataSrc is IsNestedBlock, the functions are rendered for a
+// nested block object (see nestedWithAttributeOrBlockFn), and when it is IsProvider, the functions are rendered for the
+// provider block (see providerWithAttributeOrBlockFn).
+// When the attribute or block is deprecated and opts.warnDeprecated is set, the with functions emit a warning with
+// std.trace when they are called.
+func withFnsForAttributeOrBlock(
+	providerName, typ string,
 	resrcOrDataSrc resourceOrDataSource,
-	providerName, typ, attrTFName string,
-	isMixin bool,
+	nestedName string,
+	attrTFName, attrTyp string,
 	collTyp collectionType,
-) (*j.FuncType, error) {
-	valueArgName := "value"
+	labels fieldLabels,
+	opts renderOpts,
+) ([]j.Type, error) {
+	valueExpr := valueArgName
+	if labels.isDeprecated && opts.warnDeprecated {
+		objectName := qualifiedObjectName(providerName, typ, resrcOrDataSrc)
+		valueExpr = deprecationWarning(objectName, attrTFName, valueArgName)
+	}
 
+	out := []j.Type{}
+	for _, flavor := range withFnFlavors(collTyp) {
+		doc, err := withFnDocs(
+			providerName, typ, resrcOrDataSrc, opts.group, nestedName, attrTFName, attrTyp, collTyp, labels, flavor,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		switch resrcOrDataSrc {
+		case IsNestedBlock:
+			fn, err := nestedWithAttributeOrBlockFn(attrTFName, valueExpr, flavor, collTyp)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, *doc, j.Hidden(*fn))
+			continue
+		case IsProvider:
+			fn, err := providerWithAttributeOrBlockFn(providerName, attrTFName, valueExpr, flavor, collTyp)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, *fn, j.Hidden(*doc))
+			continue
+		}
+
+		fn, err := withAttributeOrBlockFnForValue(resrcOrDataSrc, typ, attrTFName, valueExpr, flavor, collTyp)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *fn, j.Hidden(*doc))
+	}
+	return out, nil
+}
+
+// withFnFlavors returns the flavors of with functions that are supported for an attribute or block of the given
+// collection type. Mixins are only supported on collections, and patching by index is only supported on lists and sets.
+func withFnFlavors(collTyp collectionType) []withFnFlavor {
+	switch collTyp {
+	case IsMap:
+		return []withFnFlavor{IsSetter, IsMixin}
+	case IsListOrSet:
+		return []withFnFlavor{IsSetter, IsMixin, IsMixinAt}
+	}
+	return []withFnFlavor{IsSetter}
+}
+
+// withAttributeOrBlockFnForValue returns the function implementation for the with function of the given flavor, which
+// returns a mixin to set or update the given attribute or block on the resource or data source in the root terraform
+// document. The attribute or block is set to the given jsonnet expression, which is usually the value arg. This allows
+// the with function to preprocess or validate the value arg before it is set.
+func withAttributeOrBlockFnForValue(
+	resrcOrDataSrc resourceOrDataSource,
+	typ, attrTFName, valueExpr string,
+	flavor withFnFlavor,
+	collTyp collectionType,
+) (*j.FuncType, error) {
 	// NOTE: this is a hack to work around the lack of functionality to introduce a reference key merge in the builder
 	// library. This takes advantage of a quirk where the builder outputs the literal string name of the object as the key
 	// for the merge operation. So using the reference name wrapped in [] as the merge key results in the literal
@@ -241,73 +339,166 @@ func withAttributeOrBlockFn(
 	// The maintainers of the k8s generator library may change this behavior in the future!
 	refMerge := fmt.Sprintf("[%s]", resrcOrDataSrc.labelArg())
 
-	fnName := fmt.Sprintf("with%s", strcase.ToCamel(attrTFName))
-	var attrRef j.Type = j.Ref(attrTFName, valueArgName)
+	attrRef, err := withFnSetterForValue(attrTFName, valueExpr, flavor, collTyp)
+	if err != nil {
+		return nil, err
+	}
+
+	result := j.Object("",
+		j.Merge(j.Object(resrcOrDataSrc.injectAttrName(),
+			j.Merge(j.Object(typ,
+				j.Merge(j.Object(refMerge,
+					attrRef)))))))
+	fn := j.Func(flavor.fnName(attrTFName),
+		j.Args(withFnArgs(resrcOrDataSrc.labelArg(), flavor)...),
+		result,
+	)
+	return &fn, nil
+}
+
+// nestedWithAttributeOrBlockFn returns the function implementation for the with function of the given flavor on a
+// nested block object. Unlike withAttributeOrBlockFnForValue, the returned mixin is relative to the nested block, and is meant
+// to be passed in as the value to the with functions of the parent block. This allows updating blocks at any depth.
+// The attribute or block is set to the given jsonnet expression, which is usually the value arg.
+func nestedWithAttributeOrBlockFn(
+	attrTFName, valueExpr string,
+	flavor withFnFlavor,
+	collTyp collectionType,
+) (*j.FuncType, error) {
+	attrRef, err := withFnSetterForValue(attrTFName, valueExpr, flavor, collTyp)
+	if err != nil {
+		return nil, err
+	}
 
-	if isMixin {
-		fnName = fnName + "Mixin"
+	fn := j.Func(flavor.fnName(attrTFName),
+		j.Args(withFnArgs("", flavor)...),
+		j.Object("", attrRef),
+	)
+	return &fn, nil
+}
+
+// withFnArgs returns the list of args for a with function of the given flavor. The label arg is omitted when
+// labelArgName is empty.
+func withFnArgs(labelArgName string, flavor withFnFlavor) []j.Type {
+	args := []j.Type{}
+	if labelArgName != "" {
+		args = append(args, j.Required(j.String(labelArgName, "")))
+	}
+	if flavor == IsMixinAt {
+		args = append(args, j.Required(j.String(indexArgName, "")))
+	}
+	args = append(args, j.Required(j.String(valueArgName, "")))
+	return args
+}
+
+// withFnSetterForValue returns the object field that sets, merges, or patches the given attribute or block with the
+// given jsonnet expression (usually the value arg), depending on the flavor of the with function.
+func withFnSetterForValue(
+	attrTFName, valueExpr string,
+	flavor withFnFlavor,
+	collTyp collectionType,
+) (j.Type, error) {
+	var attrRef j.Type = j.Ref(attrTFName, valueExpr)
+
+	switch flavor {
+	case IsSetter:
+		return attrRef, nil
+
+	case IsMixin:
 		switch collTyp {
 		case IsMap:
-			attrRef = j.Merge(attrRef)
+			return j.Merge(attrRef), nil
 		case IsListOrSet:
 			// For lists or sets, we want to conditionally convert the arg to a list so that it can be appended.
 			conditional := j.IfThenElse(attrTFName,
-				j.Call("", "std.isArray", []j.Type{j.Ref("v", valueArgName)}),
+				j.Call("", "std.isArray", []j.Type{j.Ref("v", valueExpr)}),
 				attrRef,
 				j.List("", attrRef),
 			)
-			attrRef = j.Merge(conditional)
-		default:
-			return nil, fmt.Errorf("Mixin function for attribute %s with collection type %s is not supported", attrTFName, collTyp)
+			return j.Merge(conditional), nil
 		}
-	}
 
-	result := j.Object("",
-		j.Merge(j.Object(resrcOrDataSrc.injectAttrName(),
-			j.Merge(j.Object(typ,
-				j.Merge(j.Object(refMerge,
-					attrRef)))))))
-	fn := j.Func(fnName,
-		j.Args(
-			j.Required(j.String(resrcOrDataSrc.labelArg(), "")),
-			j.Required(j.String(valueArgName, "")),
-		),
-		result,
+	case IsMixinAt:
+		if collTyp == IsListOrSet {
+			// Merge the value into the element at the given index, leaving the other elements as is. Note that super refers
+			// to the existing list here, since the resulting object is always merged into the parent block.
+			patched := j.Call(attrTFName, "std.mapWithIndex", []j.Type{
+				j.Ref("func", fmt.Sprintf(
+					"function(i, elem) if i == %s then elem + %s else elem",
+					indexArgName, valueExpr,
+				)),
+				j.Ref("arr", fmt.Sprintf("super[%q]", attrTFName)),
+			})
+			return patched, nil
+		}
+	}
+	return nil, fmt.Errorf(
+		"%s function for attribute %s with collection type %s is not supported",
+		flavor, attrTFName, collTyp,
 	)
-	return &fn, nil
 }
 
 // nestedBlockObject renders the object with functions for constructing and modifying nested blocks on the resource or
-// data source.
-// For now, this is just the constructors. In the future, we may add mixin objects, but these are currently not
-// implemented due to the complexity involved in setting up the merge operators correctly across the nested levels.
+// data source. This is also used for attributes with nested attribute types, which are represented as blocks (see
+// getNestedAttributeTypes).
+// In addition to the constructor, the object contains with functions for each attribute and sub block of the nested
+// block. These return mixins relative to the nested block, so that they can be passed in as the value to the mixin
+// functions of the parent (e.g., withBLOCKMixin for single blocks, and withBLOCKMixinAt for list and set blocks) to
+// update a nested block at any depth.
 // nestedName tracks the number of nesting that has occurred, and is used for constructing the relative links in
 // the docsonnet docs. This should represent the level at the current object, and should include the nested block name.
-func nestedBlockObject(providerName, nestedName string, cfg *block) (j.Type, error) {
+func nestedBlockObject(providerName, nestedName string, cfg *block, opts renderOpts) (j.Type, error) {
 	errRet := j.Null(cfg.tfName)
 	objFields := sortedTypeList{}
 
 	constructorDocs, err := attrsConstructorDocs(
-		providerName, cfg.tfName, IsNestedBlock, constructorFnName, nestedName, cfg.block.Block,
+		providerName, cfg.tfName, IsNestedBlock, "", constructorFnName, nestedName, cfg.block.Block,
 	)
 	if err != nil {
 		return errRet, err
 	}
 	constructor, err := attrsConstructor(
-		constructorFnName, providerName, cfg.tfName, IsNestedBlock, cfg.block.Block,
+		constructorFnName, providerName, cfg.tfName, IsNestedBlock, cfg.block.Block, opts,
 	)
 	if err != nil {
 		return errRet, err
 	}
 	objFields = append(objFields, *constructorDocs, j.Hidden(*constructor))
 
-	// Add nested objects for deep nested blocks as well.
-	for _, nestedCfg := range getNestedBlocks(cfg.block.Block) {
+	// Add modifier functions for each attribute and sub block.
+	for _, attrCfg := range getInputAttributes(cfg.block.Block) {
+		collTyp := IsNotCollection
+		if attrCfg.attr.AttributeNestedType != nil {
+			collTyp = getCollectionType(attrCfg.attr.AttributeNestedType.NestingMode)
+		}
+		withFns, err := withFnsForAttributeOrBlock(
+			providerName, cfg.tfName, IsNestedBlock, nestedName, attrCfg.tfName, getAttrType(attrCfg.attr), collTyp,
+			attrCfg.labels(), opts,
+		)
+		if err != nil {
+			return errRet, err
+		}
+		objFields = append(objFields, withFns...)
+	}
+	for _, blockCfg := range getNestedBlocks(cfg.block.Block) {
+		withFns, err := withFnsForAttributeOrBlock(
+			providerName, cfg.tfName, IsNestedBlock, nestedName, blockCfg.tfName, getBlockType(blockCfg.block.NestingMode),
+			getCollectionType(blockCfg.block.NestingMode), blockCfg.labels(), opts,
+		)
+		if err != nil {
+			return errRet, err
+		}
+		objFields = append(objFields, withFns...)
+	}
+
+	// Add nested objects for deep nested blocks and nested attribute types as well.
+	for _, nestedCfg := range getNestedObjects(cfg.block.Block) {
 		providerNameForNested := fmt.Sprintf("%s.%s", providerName, cfg.tfName)
 		deepNestedBlockObj, err := nestedBlockObject(
 			providerNameForNested,
-			nestedName+cfg.tfName,
+			nestedName+nestedCfg.tfName,
 			nestedCfg,
+			opts,
 		)
 		if err != nil {
 			return errRet, err
@@ -7,12 +7,31 @@ import (
 
 	. "github.com/onsi/gomega"
 
+	"github.com/google/go-jsonnet"
 	"github.com/google/go-jsonnet/formatter"
 	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
 )
 
 const (
 	tfcoremockSchemaF = "fixtures/tfcoremock_schema.json"
+
+	// stubCoreLibsonnet and stubDocsonnetLibsonnet are minimal stand-ins for the core and docsonnet libraries, so that
+	// the rendered code can be evaluated without vendoring the libraries with jb.
+	stubCoreLibsonnet = `{
+  withResource(type, label, attrs, _meta={}):: { resource+: { [type]+: { [label]: attrs } } },
+  withData(type, label, attrs, _meta={}):: { data+: { [type]+: { [label]: attrs } } },
+  withProvider(name, attrs, alias=null, src=null, version=null):: {
+    provider+: { [name]+: [attrs + (if alias != null then { alias: alias } else {})] },
+  },
+}`
+	stubDocsonnetLibsonnet = `{
+  fn(help, args=[]):: { 'function': { help: help, args: args } },
+  arg(name, type, default=null, enums=null):: { name: name, type: type, default: default },
+  T:: { any: 'any', array: 'array', boolean: 'bool', number: 'number', object: 'object', string: 'string' },
+  obj(help, fields={}):: {},
+  pkg(name, url, help, filename='', version=''):: {},
+}`
 )
 
 func TestRenderResourceComplex(t *testing.T) {
@@ -23,7 +42,7 @@ func TestRenderResourceComplex(t *testing.T) {
 	complexResource := schema.ResourceSchemas["tfcoremock_complex_resource"]
 
 	jt, err := renderResourceOrDataSource(
-		"tfcoremock", "tfcoremock_complex_resource", IsResource, complexResource.Block,
+		"tfcoremock", "tfcoremock_complex_resource", IsResource, complexResource.Block, renderOpts{},
 	)
 	g.Expect(err).NotTo(HaveOccurred())
 
@@ -41,7 +60,7 @@ func TestRenderResourceSimple(t *testing.T) {
 	simpleResource := schema.ResourceSchemas["tfcoremock_simple_resource"]
 
 	jt, err := renderResourceOrDataSource(
-		"tfcoremock", "tfcoremock_simple_resource", IsResource, simpleResource.Block,
+		"tfcoremock", "tfcoremock_simple_resource", IsResource, simpleResource.Block, renderOpts{},
 	)
 	g.Expect(err).NotTo(HaveOccurred())
 
@@ -59,7 +78,7 @@ func TestRenderDataSourceSimple(t *testing.T) {
 	simpleResource := schema.DataSourceSchemas["tfcoremock_simple_resource"]
 
 	jt, err := renderResourceOrDataSource(
-		"tfcoremock", "tfcoremock_simple_resource", IsDataSource, simpleResource.Block,
+		"tfcoremock", "tfcoremock_simple_resource", IsDataSource, simpleResource.Block, renderOpts{},
 	)
 	g.Expect(err).NotTo(HaveOccurred())
 
@@ -77,7 +96,7 @@ func TestRenderDataSourceComplex(t *testing.T) {
 	complexResource := schema.DataSourceSchemas["tfcoremock_complex_resource"]
 
 	jt, err := renderResourceOrDataSource(
-		"tfcoremock", "tfcoremock_complex_resource", IsDataSource, complexResource.Block,
+		"tfcoremock", "tfcoremock_complex_resource", IsDataSource, complexResource.Block, renderOpts{},
 	)
 	g.Expect(err).NotTo(HaveOccurred())
 
@@ -87,6 +106,105 @@ func TestRenderDataSourceComplex(t *testing.T) {
 	t.Logf(out)
 }
 
+func TestRenderResourceNestedAttributeTypes(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	complexResource := schema.ResourceSchemas["tfcoremock_complex_resource"]
+
+	jt, err := renderResourceOrDataSource(
+		"tfcoremock", "tfcoremock_complex_resource", IsResource, complexResource.Block, renderOpts{},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Exercise the constructors for nested attributes at every nesting mode, including deeply nested attributes.
+	out := evalRenderedDoc(g, jt.String(), `
+local r = import 'resource.libsonnet';
+{
+  list: r.list.new(string='a'),
+  set: r.set.new(number=1),
+  object: r.object.new(bool=true, list=[r.object.list.new(integer=2)]),
+  mixin: r.withMapMixin('foo', { k: r.map.new(string='v') }),
+}
+`)
+	g.Expect(out).To(MatchJSON(`{
+  "list": {"string": "a"},
+  "set": {"number": 1},
+  "object": {"bool": true, "list": [{"integer": 2}]},
+  "mixin": {"resource": {"tfcoremock_complex_resource": {"foo": {"map": {"k": {"string": "v"}}}}}}
+}`))
+}
+
+func TestRenderResourceNestedBlockMixins(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	complexResource := schema.ResourceSchemas["tfcoremock_complex_resource"]
+
+	jt, err := renderResourceOrDataSource(
+		"tfcoremock", "tfcoremock_complex_resource", IsResource, complexResource.Block, renderOpts{},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Patch fields of existing nested blocks and objects at multiple levels, without rebuilding the blocks.
+	out := evalRenderedDoc(g, jt.String(), `
+local r = import 'resource.libsonnet';
+r.new(
+  'foo',
+  list_block=[
+    r.list_block.new(string='a', list_block=[r.list_block.list_block.new(string='x')]),
+    r.list_block.new(string='b'),
+  ],
+  object=r.object.new(string='o'),
+)
++ r.withListBlockMixinAt(
+  'foo',
+  0,
+  r.list_block.withBool(true)
+  + r.list_block.withListBlockMixinAt(0, r.list_block.list_block.withNumber(1)),
+)
++ r.withListBlockMixin('foo', r.list_block.new(string='c'))
++ r.withObjectMixin('foo', r.object.withNumber(2))
+`)
+	g.Expect(out).To(MatchJSON(`{
+  "resource": {
+    "tfcoremock_complex_resource": {
+      "foo": {
+        "list_block": [
+          {"string": "a", "bool": true, "list_block": [{"string": "x", "number": 1}]},
+          {"string": "b"},
+          {"string": "c"}
+        ],
+        "object": {"string": "o", "number": 2}
+      }
+    }
+  }
+}`))
+}
+
+// evalRenderedDoc evaluates the given jsonnet snippet, which can import the rendered libsonnet code as
+// resource.libsonnet.
+func evalRenderedDoc(g *WithT, rendered, snippet string) string {
+	out, err := renderedDocVM(rendered).EvaluateAnonymousSnippet("test.jsonnet", snippet)
+	g.Expect(err).NotTo(HaveOccurred())
+	return out
+}
+
+// renderedDocVM returns a jsonnet VM that can import the rendered libsonnet code as resource.libsonnet.
+func renderedDocVM(rendered string) *jsonnet.VM {
+	vm := jsonnet.MakeVM()
+	vm.Importer(&jsonnet.MemoryImporter{
+		Data: map[string]jsonnet.Contents{
+			"resource.libsonnet":                                        jsonnet.MakeContents(rendered),
+			"github.com/tf-libsonnet/core/main.libsonnet":               jsonnet.MakeContents(stubCoreLibsonnet),
+			"github.com/jsonnet-libs/docsonnet/doc-util/main.libsonnet": jsonnet.MakeContents(stubDocsonnetLibsonnet),
+		},
+	})
+	return vm
+}
+
 func loadSchema(g *WithT, fixturePath string) *tfjson.ProviderSchema {
 	data, err := os.ReadFile(tfcoremockSchemaF)
 	g.Expect(err).NotTo(HaveOccurred())
@@ -97,3 +215,258 @@ func loadSchema(g *WithT, fixturePath string) *tfjson.ProviderSchema {
 
 	return &schema
 }
+
+func TestRenderResourceRef(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	complexResource := schema.ResourceSchemas["tfcoremock_complex_resource"]
+
+	jt, err := renderResourceOrDataSource(
+		"tfcoremock", "tfcoremock_complex_resource", IsResource, complexResource.Block, renderOpts{},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	out := evalRenderedDoc(g, jt.String(), `
+local r = import 'resource.libsonnet';
+{
+  id: r.ref('foo', 'id'),
+  nestedBlock: r.ref('foo', 'list_block[0].list_block[1].string'),
+  nestedAttr: r.ref('foo', 'object.list[0].integer'),
+  mapAttr: r.ref('foo', 'map["k"].string'),
+}
+`)
+	g.Expect(out).To(MatchJSON(`{
+  "id": "${tfcoremock_complex_resource.foo.id}",
+  "nestedBlock": "${tfcoremock_complex_resource.foo.list_block[0].list_block[1].string}",
+  "nestedAttr": "${tfcoremock_complex_resource.foo.object.list[0].integer}",
+  "mapAttr": "${tfcoremock_complex_resource.foo.map[\"k\"].string}"
+}`))
+
+	// Typos in the attribute path should fail the evaluation.
+	_, evalErr := renderedDocVM(jt.String()).EvaluateAnonymousSnippet(
+		"test.jsonnet", `(import 'resource.libsonnet').ref('foo', 'list_block[0].strnig')`,
+	)
+	g.Expect(evalErr).To(MatchError(ContainSubstring("tfcoremock_complex_resource does not have an attribute")))
+}
+
+func TestRenderDataSourceRef(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	simpleDataSrc := schema.DataSourceSchemas["tfcoremock_simple_resource"]
+
+	jt, err := renderResourceOrDataSource(
+		"tfcoremock", "tfcoremock_simple_resource", IsDataSource, simpleDataSrc.Block, renderOpts{},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	out := evalRenderedDoc(g, jt.String(), `(import 'resource.libsonnet').ref('foo', 'id')`)
+	g.Expect(out).To(MatchJSON(`"${data.tfcoremock_simple_resource.foo.id}"`))
+}
+
+func TestRenderResourceMetaArguments(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	simpleResource := schema.ResourceSchemas["tfcoremock_simple_resource"]
+
+	jt, err := renderResourceOrDataSource(
+		"tfcoremock", "tfcoremock_simple_resource", IsResource, simpleResource.Block, renderOpts{},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	out := evalRenderedDoc(g, jt.String(), `
+local r = import 'resource.libsonnet';
+r.new('foo')
++ r.withCount('foo', 2)
++ r.withDependsOn('foo', ['tfcoremock_simple_resource.bar'])
++ r.withDependsOnMixin('foo', 'tfcoremock_simple_resource.baz')
++ r.withProvider('foo', 'tfcoremock.alt')
++ r.withLifecycle('foo', { create_before_destroy: true })
++ r.withLifecycleMixin('foo', { prevent_destroy: true })
++ r.withProvisioner('foo', [{ 'local-exec': { command: 'echo' } }])
+`)
+	g.Expect(out).To(MatchJSON(`{
+  "resource": {
+    "tfcoremock_simple_resource": {
+      "foo": {
+        "count": 2,
+        "depends_on": ["tfcoremock_simple_resource.bar", "tfcoremock_simple_resource.baz"],
+        "provider": "tfcoremock.alt",
+        "lifecycle": {"create_before_destroy": true, "prevent_destroy": true},
+        "provisioner": [{"local-exec": {"command": "echo"}}]
+      }
+    }
+  }
+}`))
+}
+
+func TestRenderDataSourceMetaArguments(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	simpleDataSrc := schema.DataSourceSchemas["tfcoremock_simple_resource"]
+
+	jt, err := renderResourceOrDataSource(
+		"tfcoremock", "tfcoremock_simple_resource", IsDataSource, simpleDataSrc.Block, renderOpts{},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	out := evalRenderedDoc(g, jt.String(), `
+local r = import 'resource.libsonnet';
+{
+  forEach: r.withForEach('foo', { a: 'b' }),
+  hasProvisioner: std.objectHasAll(r, 'withProvisioner'),
+  hasConnection: std.objectHasAll(r, 'withConnection'),
+}
+`)
+	g.Expect(out).To(MatchJSON(`{
+  "forEach": {"data": {"tfcoremock_simple_resource": {"foo": {"for_each": {"a": "b"}}}}},
+  "hasProvisioner": false,
+  "hasConnection": false
+}`))
+}
+
+func TestRenderResourceTimeouts(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := &tfjson.SchemaBlock{
+		Attributes: map[string]*tfjson.SchemaAttribute{
+			"name": {AttributeType: cty.String, Required: true},
+		},
+		NestedBlocks: map[string]*tfjson.SchemaBlockType{
+			"timeouts": {
+				NestingMode: tfjson.SchemaNestingModeSingle,
+				Block: &tfjson.SchemaBlock{
+					Attributes: map[string]*tfjson.SchemaAttribute{
+						"create": {AttributeType: cty.String, Optional: true},
+						"delete": {AttributeType: cty.String, Optional: true},
+					},
+				},
+			},
+		},
+	}
+
+	jt, err := renderResourceOrDataSource("test", "test_resource", IsResource, schema, renderOpts{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(jt.String()).To(ContainSubstring("  - `create`"))
+
+	out := evalRenderedDoc(g, jt.String(), `
+local r = import 'resource.libsonnet';
+r.new('foo', name='foo')
++ r.withTimeouts('foo', { create: '1h30m' })
++ r.withTimeoutsMixin('foo', { delete: '30s' })
+`)
+	g.Expect(out).To(MatchJSON(`{
+  "resource": {
+    "test_resource": {
+      "foo": {"name": "foo", "timeouts": {"create": "1h30m", "delete": "30s"}}
+    }
+  }
+}`))
+
+	vm := renderedDocVM(jt.String())
+	_, evalErr := vm.EvaluateAnonymousSnippet(
+		"test.jsonnet", `(import 'resource.libsonnet').withTimeouts('foo', { update: '10m' })`,
+	)
+	g.Expect(evalErr).To(MatchError(ContainSubstring("unknown timeouts (update): must be one of create, delete")))
+	_, evalErr = vm.EvaluateAnonymousSnippet(
+		"test.jsonnet", `(import 'resource.libsonnet').withTimeouts('foo', { create: '10 minutes' })`,
+	)
+	g.Expect(evalErr).To(MatchError(ContainSubstring("timeouts (create) must be duration strings")))
+}
+
+func TestRenderResourceWithAssertions(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	complexResource := schema.ResourceSchemas["tfcoremock_complex_resource"]
+
+	jt, err := renderResourceOrDataSource(
+		"tfcoremock", "tfcoremock_complex_resource", IsResource, complexResource.Block, renderOpts{withAssertions: true},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Valid types, including Terraform expressions, should pass the assertions.
+	out := evalRenderedDoc(g, jt.String(), `
+local r = import 'resource.libsonnet';
+r.new('foo', bool=true, number='${var.number}', list_block=[r.list_block.new(string='a')])
+`)
+	g.Expect(out).To(MatchJSON(`{
+  "resource": {
+    "tfcoremock_complex_resource": {
+      "foo": {"bool": true, "number": "${var.number}", "list_block": [{"string": "a"}]}
+    }
+  }
+}`))
+
+	vm := renderedDocVM(jt.String())
+	_, evalErr := vm.EvaluateAnonymousSnippet(
+		"test.jsonnet", `(import 'resource.libsonnet').new('foo', bool=1)`,
+	)
+	g.Expect(evalErr).To(MatchError(ContainSubstring(
+		"tfcoremock_complex_resource.bool must be a bool or an expression string, got number",
+	)))
+	_, evalErr = vm.EvaluateAnonymousSnippet(
+		"test.jsonnet", `(import 'resource.libsonnet').list_block.new(string=true)`,
+	)
+	g.Expect(evalErr).To(MatchError(ContainSubstring(
+		"tfcoremock.complex_resource.list_block.string must be a string, got boolean",
+	)))
+	_, evalErr = vm.EvaluateAnonymousSnippet(
+		"test.jsonnet", `(import 'resource.libsonnet').new('foo', set_block='not a block')`,
+	)
+	g.Expect(evalErr).To(MatchError(ContainSubstring(
+		"tfcoremock_complex_resource.set_block must be an array or an object, got string",
+	)))
+}
+
+func TestRenderResourceDocArgs(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	schema := loadSchema(g, tfcoremockSchemaF)
+	complexResource := schema.ResourceSchemas["tfcoremock_complex_resource"]
+
+	jt, err := renderResourceOrDataSource(
+		"tfcoremock", "tfcoremock_complex_resource", IsResource, complexResource.Block, renderOpts{},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	out := evalRenderedDoc(g, jt.String(), `
+local r = import 'resource.libsonnet';
+local args(fn) = [a.name + ':' + a.type for a in fn['function'].args];
+{
+  new: args(r['#new']),
+  newAttrs: args(r['#newAttrs']),
+  withBool: args(r['#withBool']),
+  withListBlockMixinAt: args(r['#withListBlockMixinAt']),
+  nestedWithString: args(r.list_block['#withString']),
+  ref: args(r['#ref']),
+  metaDefault: r['#new']['function'].args[std.length(r['#new']['function'].args) - 1].default,
+}
+`)
+	g.Expect(out).To(MatchJSON(`{
+  "new": [
+    "resourceLabel:string", "bool:bool", "float:number", "integer:number", "list:array",
+    "list_block:array", "map:object", "number:number", "object:object", "set:array", "set_block:array",
+    "string:string", "_meta:object"
+  ],
+  "newAttrs": [
+    "bool:bool", "float:number", "integer:number", "list:array", "list_block:array", "map:object",
+    "number:number", "object:object", "set:array", "set_block:array", "string:string"
+  ],
+  "withBool": ["resourceLabel:string", "value:bool"],
+  "withListBlockMixinAt": ["resourceLabel:string", "index:number", "value:object"],
+  "nestedWithString": ["value:string"],
+  "ref": ["resourceLabel:string", "attr:string"],
+  "metaDefault": {}
+}`))
+}
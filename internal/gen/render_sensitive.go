@@ -0,0 +1,56 @@
+package gen
+
+import (
+	"sort"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	j "github.com/jsonnet-libs/k8s/pkg/builder"
+)
+
+const (
+	sensitiveAttrsFnName = "sensitiveAttrs"
+)
+
+// sensitiveAttrsFn returns the function implementation for the sensitiveAttrs function, which returns the paths of all
+// the attributes that are marked as sensitive or write-only in the given schema. This can be used to check that these
+// attributes are not set to hard-coded literals in the Jsonnet code.
+func sensitiveAttrsFn(schema *tfjson.SchemaBlock) j.FuncType {
+	return j.Func(
+		sensitiveAttrsFnName,
+		j.Args(),
+		j.Ref("", quotedList(getSensitivePaths(schema))),
+	)
+}
+
+// getSensitivePaths returns the sorted list of the paths to all the attributes that are marked as sensitive or
+// write-only in the schema, including those in nested blocks and nested attribute types. Write-only attributes are
+// included since they are used for secrets that should not be persisted in the state. Nested paths are joined with `.`.
+func getSensitivePaths(schema *tfjson.SchemaBlock) []string {
+	paths := []string{}
+	collectSensitivePaths("", schema.Attributes, schema.NestedBlocks, &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+func collectSensitivePaths(
+	prefix string,
+	attrs map[string]*tfjson.SchemaAttribute,
+	blocks map[string]*tfjson.SchemaBlockType,
+	paths *[]string,
+) {
+	for name, cfg := range attrs {
+		path := prefix + name
+		if cfg.Sensitive || cfg.WriteOnly {
+			*paths = append(*paths, path)
+		}
+		if cfg.AttributeNestedType != nil {
+			collectSensitivePaths(path+".", cfg.AttributeNestedType.Attributes, nil, paths)
+		}
+	}
+
+	for name, cfg := range blocks {
+		if cfg.Block != nil {
+			collectSensitivePaths(prefix+name+".", cfg.Block.Attributes, cfg.Block.NestedBlocks, paths)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package gen
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func sensitiveTestSchema() *tfjson.SchemaBlock {
+	return &tfjson.SchemaBlock{
+		Attributes: map[string]*tfjson.SchemaAttribute{
+			"name":     {AttributeType: cty.String, Required: true},
+			"password": {AttributeType: cty.String, Optional: true, Sensitive: true},
+			"api_key":  {AttributeType: cty.String, Optional: true, WriteOnly: true},
+			"credentials": {
+				Optional: true,
+				AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+					NestingMode: tfjson.SchemaNestingModeSingle,
+					Attributes: map[string]*tfjson.SchemaAttribute{
+						"user":  {AttributeType: cty.String, Optional: true},
+						"token": {AttributeType: cty.String, Optional: true, Sensitive: true},
+					},
+				},
+			},
+		},
+		NestedBlocks: map[string]*tfjson.SchemaBlockType{
+			"auth": {
+				NestingMode: tfjson.SchemaNestingModeList,
+				Block: &tfjson.SchemaBlock{
+					Attributes: map[string]*tfjson.SchemaAttribute{
+						"secret":   {AttributeType: cty.String, Optional: true, Sensitive: true},
+						"password": {AttributeType: cty.String, Optional: true, WriteOnly: true},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestRenderResourceSensitiveDocs(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	jt, err := renderResourceOrDataSource("test", "test_resource", IsResource, sensitiveTestSchema(), renderOpts{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	out := jt.String()
+	g.Expect(out).To(ContainSubstring("`password` (`string`): **Sensitive**."))
+	g.Expect(out).To(ContainSubstring("**Sensitive**: the `password` field is marked as sensitive by the provider"))
+	g.Expect(out).To(ContainSubstring("**Sensitive**: the `secret` field is marked as sensitive by the provider"))
+	g.Expect(out).To(ContainSubstring("`api_key` (`string`): **Write-only**."))
+	g.Expect(out).To(ContainSubstring("**Write-only**: the `api_key` field is marked as write-only by the provider"))
+	g.Expect(out).To(ContainSubstring("**Write-only**: the `password` field is marked as write-only by the provider"))
+	g.Expect(out).NotTo(ContainSubstring("`name` (`string`): **Write-only**."))
+	g.Expect(out).NotTo(ContainSubstring(sensitiveAttrsFnName))
+}
+
+func TestRenderResourceSensitiveAttrs(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	jt, err := renderResourceOrDataSource(
+		"test", "test_resource", IsResource, sensitiveTestSchema(), renderOpts{withSensitiveAttrs: true},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	out := evalRenderedDoc(g, jt.String(), `(import 'resource.libsonnet').sensitiveAttrs()`)
+	g.Expect(out).To(MatchJSON(`["api_key", "auth.password", "auth.secret", "credentials.token", "password"]`))
+}
+
+func TestRenderProviderSensitiveAttrs(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	jt, err := renderProvider("test", sensitiveTestSchema(), renderOpts{withSensitiveAttrs: true})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	out := evalRenderedDoc(g, jt.String(), `(import 'resource.libsonnet').sensitiveAttrs()`)
+	g.Expect(out).To(MatchJSON(`["api_key", "auth.password", "auth.secret", "credentials.token", "password"]`))
+}
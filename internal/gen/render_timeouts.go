@@ -0,0 +1,105 @@
+package gen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	j "github.com/jsonnet-libs/k8s/pkg/builder"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	timeoutsBlockName      = "timeouts"
+	validateTimeoutsFnName = "validateTimeouts"
+)
+
+// validateTimeoutsFn is the implementation of the local function that is used by the withTimeouts functions to check
+// the passed in timeouts at evaluation time. This checks that all the keys are valid operations for the resource, and
+// that the values look like duration strings (e.g., 30s, 10m, or 1h30m).
+const validateTimeoutsFn = `
+function(keys, value)
+  local isDuration(s) =
+    std.isString(s)
+    && std.length(s) > 0
+    && std.length(std.findSubstr(s[0], '0123456789')) > 0
+    && std.length(std.findSubstr(s[std.length(s) - 1], 'smh')) > 0
+    && std.length([c for c in std.stringChars(s) if std.length(std.findSubstr(c, '0123456789.nuµmsh')) == 0]) == 0;
+  local unknownKeys = [k for k in std.objectFields(value) if !std.setMember(k, keys)];
+  local invalidValues = [
+    k for k in std.objectFields(value) if value[k] != null && !isDuration(value[k])
+  ];
+  if !std.isObject(value) then
+    error 'timeouts must be an object'
+  else if std.length(unknownKeys) > 0 then
+    error 'unknown timeouts (%s): must be one of %s' % [std.join(', ', unknownKeys), std.join(', ', keys)]
+  else if std.length(invalidValues) > 0 then
+    error 'timeouts (%s) must be duration strings (e.g., "30s", "10m", or "1h30m")' % std.join(', ', invalidValues)
+  else
+    value`
+
+// getTimeoutsBlock returns the conventional timeouts block of the schema, if any. The timeouts block is a single
+// nested block named timeouts, where each attribute is a duration string for an operation (e.g., create, update, and
+// delete). Returns nil if the schema does not have a timeouts block that follows the convention.
+func getTimeoutsBlock(schema *tfjson.SchemaBlock) *tfjson.SchemaBlockType {
+	cfg, hasTimeouts := schema.NestedBlocks[timeoutsBlockName]
+	if !hasTimeouts || cfg.Block == nil {
+		return nil
+	}
+	if cfg.NestingMode != tfjson.SchemaNestingModeSingle || len(cfg.Block.NestedBlocks) > 0 {
+		return nil
+	}
+	if len(cfg.Block.Attributes) == 0 {
+		return nil
+	}
+	for _, attr := range cfg.Block.Attributes {
+		if attr.AttributeNestedType != nil || attr.AttributeType != cty.String {
+			return nil
+		}
+	}
+	return cfg
+}
+
+// getTimeoutsKeys returns the sorted list of operation keys that can be set on the timeouts block.
+func getTimeoutsKeys(cfg *tfjson.SchemaBlockType) []string {
+	keys := make([]string, 0, len(cfg.Block.Attributes))
+	for k := range cfg.Block.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateTimeoutsLocal returns the local for the function that validates the timeouts at evaluation time. This must
+// be added to the document locals when the timeouts with functions are rendered.
+func validateTimeoutsLocal() j.LocalType {
+	return j.Local(j.Ref(validateTimeoutsFnName, strings.TrimSpace(validateTimeoutsFn)))
+}
+
+// timeoutsWithFns returns the with functions, along with their docsonnet docs, for the conventional timeouts block of
+// the resource or data source. Unlike the generic with functions for blocks, these validate the passed in timeouts
+// against the operation keys from the schema.
+func timeoutsWithFns(
+	providerName, typ string,
+	resrcOrDataSrc resourceOrDataSource,
+	group string,
+	cfg *tfjson.SchemaBlockType,
+) ([]j.Type, error) {
+	keys := getTimeoutsKeys(cfg)
+	valueExpr := fmt.Sprintf("%s(%s, %s)", validateTimeoutsFnName, quotedList(keys), valueArgName)
+
+	out := []j.Type{}
+	for _, flavor := range withFnFlavors(IsMap) {
+		doc, err := timeoutsWithFnDocs(providerName, typ, resrcOrDataSrc, group, keys, flavor)
+		if err != nil {
+			return nil, err
+		}
+		fn, err := withAttributeOrBlockFnForValue(resrcOrDataSrc, typ, timeoutsBlockName, valueExpr, flavor, IsMap)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *fn, j.Hidden(*doc))
+	}
+	return out, nil
+}
@@ -0,0 +1,122 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	stagingDirPattern = ".libgenerator-staging-*"
+	backupDirPattern  = ".previous-*"
+)
+
+// newStagingDir creates a new staging directory to render the library into. The staging directory is created within
+// outDir so that it is on the same filesystem, which allows the rendered files to be moved into place with a rename.
+func newStagingDir(outDir string) (string, error) {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(outDir, stagingDirPattern)
+}
+
+// managedLibEntries are the top level files and directories in the library that are managed by the generator, in the
+// order that they are committed.
+var managedLibEntries = []string{libRootDirName, libDocsDirName, mainLibsonnetName}
+
+// commitStagingDir moves the generator managed files and directories (see managedLibEntries) in the staging directory
+// into outDir, replacing the existing ones. Managed entries that exist in outDir but were not rendered into the staging
+// directory (e.g., the docs folder when the library is no longer rendered with docs) are removed. Files not managed by
+// the generator in outDir are left untouched.
+//
+// Directories can not be replaced with a single rename, so the existing entries are first moved into a backup
+// directory within the staging directory. If any of the entries fail to move, the entries that were already moved are
+// rolled back using the backups, so that the library is left as it was. The caller is expected to remove the staging
+// directory (and thus the backup) after this returns.
+func commitStagingDir(stagingDir, outDir string) error {
+	return commitStagingDirWithRename(stagingDir, outDir, os.Rename)
+}
+
+// commitStagingDirWithRename is the same as commitStagingDir, except the given function is used to move the files and
+// directories. This allows testing the rollback when a move fails.
+func commitStagingDirWithRename(stagingDir, outDir string, rename func(oldpath, newpath string) error) error {
+	// Make sure that all the rendered entries are managed, so that none are silently left behind in the staging
+	// directory.
+	entries, err := os.ReadDir(stagingDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !isManagedLibEntry(e.Name()) {
+			return fmt.Errorf("unexpected entry %s in staging directory %s", e.Name(), stagingDir)
+		}
+	}
+
+	backupDir, err := os.MkdirTemp(stagingDir, backupDirPattern)
+	if err != nil {
+		return err
+	}
+
+	// committed records the moves that were made so far, so that they can be undone in reverse order on failure.
+	type move struct{ from, to string }
+	committed := []move{}
+	rollback := func(err error) error {
+		for i := len(committed) - 1; i >= 0; i-- {
+			m := committed[i]
+			if restoreErr := rename(m.to, m.from); restoreErr != nil {
+				return fmt.Errorf("%w (error restoring %s: %s)", err, m.from, restoreErr)
+			}
+		}
+		return err
+	}
+
+	for _, name := range managedLibEntries {
+		src := filepath.Join(stagingDir, name)
+		dst := filepath.Join(outDir, name)
+
+		hasSrc, err := pathExists(src)
+		if err != nil {
+			return rollback(err)
+		}
+		hasDst, err := pathExists(dst)
+		if err != nil {
+			return rollback(err)
+		}
+
+		if hasDst {
+			backup := filepath.Join(backupDir, name)
+			if err := rename(dst, backup); err != nil {
+				return rollback(err)
+			}
+			committed = append(committed, move{from: dst, to: backup})
+		}
+		if hasSrc {
+			if err := rename(src, dst); err != nil {
+				return rollback(err)
+			}
+			committed = append(committed, move{from: src, to: dst})
+		}
+	}
+	return nil
+}
+
+func isManagedLibEntry(name string) bool {
+	for _, managed := range managedLibEntries {
+		if name == managed {
+			return true
+		}
+	}
+	return false
+}
+
+func pathExists(fpath string) (bool, error) {
+	_, err := os.Lstat(fpath)
+	if err == nil {
+		return true, nil
+	} else if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
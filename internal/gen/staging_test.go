@@ -0,0 +1,154 @@
+package gen
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+)
+
+func TestCommitStagingDir(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	outDir := t.TempDir()
+
+	// Setup a previously rendered library with a stale resource, and a file not managed by the generator.
+	staleFPath := filepath.Join(outDir, libRootDirName, libResourcesDirName, "stale.libsonnet")
+	g.Expect(os.MkdirAll(filepath.Dir(staleFPath), 0755)).To(Succeed())
+	g.Expect(os.WriteFile(staleFPath, []byte("{}"), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(outDir, mainLibsonnetName), []byte("old"), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(outDir, "README.md"), []byte("readme"), 0644)).To(Succeed())
+
+	// The docs from a previous render with docs enabled should be removed, since the new render has no docs.
+	staleDocsFPath := filepath.Join(outDir, libDocsDirName, docsIndexName)
+	g.Expect(os.MkdirAll(filepath.Dir(staleDocsFPath), 0755)).To(Succeed())
+	g.Expect(os.WriteFile(staleDocsFPath, []byte("stale"), 0644)).To(Succeed())
+
+	stagingDir, err := newStagingDir(outDir)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(stagingDir)
+
+	newFPath := filepath.Join(stagingDir, libRootDirName, libResourcesDirName, "new.libsonnet")
+	g.Expect(os.MkdirAll(filepath.Dir(newFPath), 0755)).To(Succeed())
+	g.Expect(os.WriteFile(newFPath, []byte("{}"), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(stagingDir, mainLibsonnetName), []byte("new"), 0644)).To(Succeed())
+
+	g.Expect(commitStagingDir(stagingDir, outDir)).To(Succeed())
+
+	g.Expect(filepath.Join(outDir, libRootDirName, libResourcesDirName, "new.libsonnet")).To(BeARegularFile())
+	g.Expect(staleFPath).NotTo(BeAnExistingFile())
+	g.Expect(os.ReadFile(filepath.Join(outDir, mainLibsonnetName))).To(Equal([]byte("new")))
+	g.Expect(filepath.Join(outDir, "README.md")).To(BeARegularFile())
+	g.Expect(filepath.Join(outDir, libDocsDirName)).NotTo(BeAnExistingFile())
+}
+
+func TestCommitStagingDirRollback(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+
+		// failSrc is the path (relative to the staging directory) of the entry that fails to move, or . to fail to back
+		// up the failDst entry in the output directory.
+		failSrc string
+		failDst string
+	}{
+		{"backup_gen", ".", libRootDirName},
+		{"install_gen", libRootDirName, libRootDirName},
+		{"backup_docs", ".", libDocsDirName},
+		{"backup_main", ".", mainLibsonnetName},
+		{"install_main", mainLibsonnetName, mainLibsonnetName},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			outDir := t.TempDir()
+
+			// Setup a previously rendered library with docs.
+			oldFPath := filepath.Join(outDir, libRootDirName, libResourcesDirName, "old.libsonnet")
+			g.Expect(os.MkdirAll(filepath.Dir(oldFPath), 0755)).To(Succeed())
+			g.Expect(os.WriteFile(oldFPath, []byte("{}"), 0644)).To(Succeed())
+			g.Expect(os.WriteFile(filepath.Join(outDir, mainLibsonnetName), []byte("old"), 0644)).To(Succeed())
+			oldDocsFPath := filepath.Join(outDir, libDocsDirName, docsIndexName)
+			g.Expect(os.MkdirAll(filepath.Dir(oldDocsFPath), 0755)).To(Succeed())
+			g.Expect(os.WriteFile(oldDocsFPath, []byte("old"), 0644)).To(Succeed())
+
+			stagingDir, err := newStagingDir(outDir)
+			g.Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(stagingDir)
+
+			newFPath := filepath.Join(stagingDir, libRootDirName, libResourcesDirName, "new.libsonnet")
+			g.Expect(os.MkdirAll(filepath.Dir(newFPath), 0755)).To(Succeed())
+			g.Expect(os.WriteFile(newFPath, []byte("{}"), 0644)).To(Succeed())
+			g.Expect(os.WriteFile(filepath.Join(stagingDir, mainLibsonnetName), []byte("new"), 0644)).To(Succeed())
+
+			// A failSrc of . refers to the output directory, which is the source when backing up an existing entry.
+			failSrc := filepath.Join(stagingDir, tc.failSrc)
+			if tc.failSrc == "." {
+				failSrc = filepath.Join(outDir, tc.failDst)
+			}
+			errRename := errors.New("rename failed")
+			rename := func(oldpath, newpath string) error {
+				if oldpath == failSrc {
+					return errRename
+				}
+				return os.Rename(oldpath, newpath)
+			}
+			g.Expect(commitStagingDirWithRename(stagingDir, outDir, rename)).To(MatchError(errRename))
+
+			// The previous library should be left as it was.
+			g.Expect(oldFPath).To(BeARegularFile())
+			g.Expect(filepath.Join(outDir, libRootDirName, libResourcesDirName, "new.libsonnet")).NotTo(BeAnExistingFile())
+			g.Expect(os.ReadFile(filepath.Join(outDir, mainLibsonnetName))).To(Equal([]byte("old")))
+			g.Expect(os.ReadFile(oldDocsFPath)).To(Equal([]byte("old")))
+		})
+	}
+}
+
+func TestCommitStagingDirUnexpectedEntry(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	outDir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(outDir, mainLibsonnetName), []byte("old"), 0644)).To(Succeed())
+
+	stagingDir, err := newStagingDir(outDir)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(stagingDir)
+	g.Expect(os.WriteFile(filepath.Join(stagingDir, mainLibsonnetName), []byte("new"), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(stagingDir, "unknown.json"), []byte("{}"), 0644)).To(Succeed())
+
+	g.Expect(commitStagingDir(stagingDir, outDir)).To(MatchError(ContainSubstring("unexpected entry unknown.json")))
+	g.Expect(os.ReadFile(filepath.Join(outDir, mainLibsonnetName))).To(Equal([]byte("old")))
+}
+
+func TestRenderLibraryCancelled(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+	logger := logging.GetSugaredLoggerForTest()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	outDir := t.TempDir()
+	opts := RenderLibraryOpts{
+		ProviderName: "tfcoremock",
+		Schema:       loadSchema(g, tfcoremockSchemaF),
+	}
+	err := RenderLibrary(logger, ctx, outDir, opts)
+	g.Expect(err).To(MatchError(context.Canceled))
+
+	// Nothing should be left behind in the output directory, including the staging directory.
+	entries, err := os.ReadDir(outDir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(BeEmpty())
+}
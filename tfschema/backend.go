@@ -0,0 +1,179 @@
+package tfschema
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	version "github.com/hashicorp/go-version"
+	install "github.com/hashicorp/hc-install"
+	"github.com/hashicorp/hc-install/fs"
+	"github.com/hashicorp/hc-install/product"
+	"github.com/hashicorp/hc-install/releases"
+	"github.com/hashicorp/hc-install/src"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"go.uber.org/zap"
+)
+
+// Backend represents the CLI tool that is used to retrieve the provider schemas.
+type Backend uint8
+
+const (
+	BackendTerraform Backend = iota
+	BackendOpenTofu
+)
+
+const (
+	terraformRegistryHost = "registry.terraform.io"
+	openTofuRegistryHost  = "registry.opentofu.org"
+	tofuBinaryName        = "tofu"
+	unknown               = "__UNKNOWN__"
+)
+
+// ParseBackend parses the given backend name. Valid names are terraform and tofu.
+func ParseBackend(name string) (Backend, error) {
+	switch name {
+	case "terraform":
+		return BackendTerraform, nil
+	case "tofu", "opentofu":
+		return BackendOpenTofu, nil
+	}
+	return BackendTerraform, fmt.Errorf("unknown backend %q: must be one of terraform or tofu", name)
+}
+
+func (b Backend) String() string {
+	switch b {
+	case BackendTerraform:
+		return "terraform"
+	case BackendOpenTofu:
+		return "tofu"
+	}
+	return unknown
+}
+
+// RegistryHost returns the hostname of the provider registry that provider addresses without an explicit hostname
+// resolve against.
+func (b Backend) RegistryHost() string {
+	switch b {
+	case BackendOpenTofu:
+		return openTofuRegistryHost
+	}
+	return terraformRegistryHost
+}
+
+// schemaBackend abstracts away how the CLI binary for retrieving the schemas is sourced, so that the routines for
+// rendering the throwaway module and running the tfexec commands can be shared across Terraform and OpenTofu.
+type schemaBackend interface {
+	// version returns the version of the CLI binary. This may be called prior to ensure so that the version can be
+	// used as a cache key without needing to install the binary.
+	version(ctx context.Context) (*version.Version, error)
+
+	// ensure returns the path to the CLI binary, installing it if necessary.
+	ensure(ctx context.Context) (string, error)
+
+	// cleanup removes any files that were installed by ensure.
+	cleanup(ctx context.Context) error
+}
+
+// newSchemaBackend returns the schemaBackend to use given the options.
+func newSchemaBackend(logger *zap.SugaredLogger, opts GetSchemasOpts) (schemaBackend, error) {
+	switch opts.Backend {
+	case BackendTerraform:
+		if opts.TerraformPath != "" {
+			return &execBackend{logger: logger, execPath: opts.TerraformPath}, nil
+		}
+		return &installedTerraformBackend{
+			logger:    logger,
+			tfVersion: opts.TerraformVersion,
+			inst:      install.NewInstaller(),
+		}, nil
+	case BackendOpenTofu:
+		tofuPath := opts.TofuPath
+		if tofuPath == "" {
+			p, err := exec.LookPath(tofuBinaryName)
+			if err != nil {
+				return nil, fmt.Errorf("could not find %s binary on PATH: %w", tofuBinaryName, err)
+			}
+			tofuPath = p
+		}
+		return &execBackend{logger: logger, execPath: tofuPath}, nil
+	}
+	return nil, fmt.Errorf("unsupported backend %s", opts.Backend)
+}
+
+// CLIVersion returns the version of the CLI binary that GetSchemas uses to retrieve the schemas given the options. This
+// does not install the binary: when installing Terraform, this is the requested TerraformVersion.
+func CLIVersion(logger *zap.SugaredLogger, ctx context.Context, opts GetSchemasOpts) (*version.Version, error) {
+	backend, err := newSchemaBackend(logger, opts)
+	if err != nil {
+		return nil, err
+	}
+	return backend.version(ctx)
+}
+
+// installedTerraformBackend is a schemaBackend that finds or installs a specific version of Terraform using
+// hc-install. If there is no matching Terraform version on the PATH, this will download one from
+// releases.hashicorp.com into a temporary directory that is removed on cleanup.
+type installedTerraformBackend struct {
+	logger    *zap.SugaredLogger
+	tfVersion *version.Version
+	inst      *install.Installer
+}
+
+func (b *installedTerraformBackend) version(ctx context.Context) (*version.Version, error) {
+	return b.tfVersion, nil
+}
+
+func (b *installedTerraformBackend) ensure(ctx context.Context) (string, error) {
+	b.logger.Debugf("Finding or installing terraform version %s", b.tfVersion)
+	return b.inst.Ensure(ctx, []src.Source{
+		&fs.ExactVersion{
+			Product: product.Terraform,
+			Version: b.tfVersion,
+		},
+		&releases.ExactVersion{
+			Product: product.Terraform,
+			Version: b.tfVersion,
+		},
+	})
+}
+
+func (b *installedTerraformBackend) cleanup(ctx context.Context) error {
+	return b.inst.Remove(ctx)
+}
+
+// execBackend is a schemaBackend that uses a CLI binary that already exists on the operator machine. The version is
+// detected by running the version command of the binary.
+type execBackend struct {
+	logger   *zap.SugaredLogger
+	execPath string
+
+	detectedVersion *version.Version
+}
+
+func (b *execBackend) version(ctx context.Context) (*version.Version, error) {
+	if b.detectedVersion != nil {
+		return b.detectedVersion, nil
+	}
+
+	tf, err := tfexec.NewTerraform(os.TempDir(), b.execPath)
+	if err != nil {
+		return nil, err
+	}
+	v, _, err := tf.Version(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	b.logger.Infof("Detected version %s for binary %s", v, b.execPath)
+	b.detectedVersion = v
+	return v, nil
+}
+
+func (b *execBackend) ensure(ctx context.Context) (string, error) {
+	return b.execPath, nil
+}
+
+func (b *execBackend) cleanup(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package tfschema
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+)
+
+func TestNewSchemaRequestForBackend(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		backend     Backend
+		provider    string
+		expectedSrc string
+	}{
+		{BackendTerraform, "null", "registry.terraform.io/hashicorp/null"},
+		{BackendTerraform, "DopplerHQ/doppler", "registry.terraform.io/dopplerhq/doppler"},
+		{BackendOpenTofu, "null", "registry.opentofu.org/hashicorp/null"},
+		{BackendOpenTofu, "DopplerHQ/doppler", "registry.opentofu.org/dopplerhq/doppler"},
+		{BackendOpenTofu, "registry.terraform.io/hashicorp/null", "registry.terraform.io/hashicorp/null"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.backend.String()+"/"+tc.provider, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			req, err := NewSchemaRequestForBackend(tc.backend, tc.provider, "")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(req.Src).To(Equal(tc.expectedSrc))
+		})
+	}
+}
+
+func TestParseBackend(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	b, err := ParseBackend("tofu")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(b).To(Equal(BackendOpenTofu))
+
+	b, err = ParseBackend("terraform")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(b).To(Equal(BackendTerraform))
+
+	_, err = ParseBackend("pulumi")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestGetSchemasTerraformPathAllCached(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	// Create a fake terraform binary that only knows how to report its version.
+	binDir := t.TempDir()
+	fakeTFPath := filepath.Join(binDir, "terraform")
+	fakeTF := "#!/bin/sh\necho '{\"terraform_version\": \"1.5.7\", \"provider_selections\": {}}'\n"
+	g.Expect(os.WriteFile(fakeTFPath, []byte(fakeTF), 0755)).To(Succeed())
+
+	cache, err := NewSchemaCache(t.TempDir())
+	g.Expect(err).NotTo(HaveOccurred())
+	src := "registry.terraform.io/hashicorp/null"
+	tfV := version.Must(version.NewVersion("1.5.7"))
+	lock := &ProviderLock{Src: src, Version: "3.2.1"}
+	g.Expect(cache.Put(lock, tfV, loadNullSchema(g))).To(Succeed())
+
+	req, err := NewSchemaRequest("null", "3.2.1")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The cache is keyed by the detected version of the binary, so this should hit the cache without needing to
+	// install Terraform.
+	logger := logging.GetSugaredLoggerForTest()
+	schemas, _, err := GetSchemas(
+		logger, context.Background(), SchemaRequestList{req},
+		GetSchemasOpts{TerraformPath: fakeTFPath, Cache: cache},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(schemas.Schemas).To(HaveKey(src))
+}
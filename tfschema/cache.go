@@ -0,0 +1,180 @@
+package tfschema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+const (
+	cacheDirName        = "libgenerator"
+	cacheSchemasDirName = "schemas"
+	cacheEntryExt       = ".json"
+)
+
+// SchemaCache represents an on-disk cache of provider schemas. Each entry in the cache is keyed by the provider source,
+// the resolved provider version, and the Terraform version that was used to extract the schema. The entries are stored
+// as files named by the hash of the key so that the schema for a given key is always stored in the same location.
+type SchemaCache struct {
+	dir string
+}
+
+// CacheEntry is the representation of a single cache entry on disk. The key components are stored alongside the
+// schema so that the cache directory can be inspected and audited by operators.
+type CacheEntry struct {
+	// Provider is the lock information of the provider version the schema was extracted from.
+	Provider *ProviderLock `json:"provider"`
+
+	// TerraformVersion is the version of the CLI that was used to extract the schema.
+	TerraformVersion string `json:"terraform_version"`
+
+	// Schema is the cached provider schema.
+	Schema *tfjson.ProviderSchema `json:"schema"`
+}
+
+// DefaultCacheDir returns the default location of the schema cache, which is a libgenerator folder in the user cache
+// directory of the operator machine (e.g., $XDG_CACHE_HOME/libgenerator or ~/.cache/libgenerator on Linux).
+func DefaultCacheDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userCacheDir, cacheDirName), nil
+}
+
+// NewSchemaCache returns a schema cache that stores the schemas in the given directory. The directory is created if it
+// does not exist.
+func NewSchemaCache(dir string) (*SchemaCache, error) {
+	if err := os.MkdirAll(filepath.Join(dir, cacheSchemasDirName), 0755); err != nil {
+		return nil, err
+	}
+	return &SchemaCache{dir: dir}, nil
+}
+
+// Dir returns the root directory of the cache.
+func (c *SchemaCache) Dir() string {
+	return c.dir
+}
+
+// Get returns the cache entry for the given provider source, provider version, and Terraform version. The boolean
+// return value indicates whether there was a cache hit.
+func (c *SchemaCache) Get(src string, providerVersion, tfVersion *version.Version) (*CacheEntry, bool, error) {
+	data, err := os.ReadFile(c.entryPath(src, providerVersion, tfVersion))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, err
+	}
+	return &entry, true, nil
+}
+
+// Put stores the schema for the given provider lock and Terraform version in the cache. The entry is keyed by the
+// provider source and resolved version in the lock. The entry is written to a temporary file first and moved into
+// place so that concurrent readers never see a partial entry.
+func (c *SchemaCache) Put(
+	lock *ProviderLock,
+	tfVersion *version.Version,
+	schema *tfjson.ProviderSchema,
+) error {
+	providerVersion, err := version.NewVersion(lock.Version)
+	if err != nil {
+		return err
+	}
+
+	entry := CacheEntry{
+		Provider:         lock,
+		TerraformVersion: tfVersion.String(),
+		Schema:           schema,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	fpath := c.entryPath(lock.Src, providerVersion, tfVersion)
+	tmpF, err := os.CreateTemp(filepath.Dir(fpath), "tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpF.Name())
+
+	if _, err := tmpF.Write(data); err != nil {
+		tmpF.Close()
+		return err
+	}
+	if err := tmpF.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpF.Name(), fpath)
+}
+
+// Prune removes all the entries in the cache that were last written before the given duration. Passing in 0 removes
+// all the entries. Returns the number of entries that were removed.
+func (c *SchemaCache) Prune(olderThan time.Duration) (int, error) {
+	schemasDir := filepath.Join(c.dir, cacheSchemasDirName)
+	entries, err := os.ReadDir(schemasDir)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), cacheEntryExt) {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return removed, err
+		}
+		if olderThan > 0 && info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(schemasDir, e.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// entryPath returns the path to the file that holds the cache entry for the given key.
+func (c *SchemaCache) entryPath(src string, providerVersion, tfVersion *version.Version) string {
+	key := fmt.Sprintf("%s\n%s\n%s", src, providerVersion, tfVersion)
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, cacheSchemasDirName, hex.EncodeToString(sum[:])+cacheEntryExt)
+}
+
+// exactVersion returns the exact version that the given version constraint pins to. The boolean return value
+// indicates whether the constraint pins to an exact version (e.g., =3.2.1 or 3.2.1). Constraints that match a range of
+// versions can not be resolved without consulting the registry, and thus can not be used for a cache lookup.
+func exactVersion(constraint string) (*version.Version, bool) {
+	c := strings.TrimSpace(constraint)
+	c = strings.TrimSpace(strings.TrimPrefix(c, "="))
+	if c == "" {
+		return nil, false
+	}
+
+	v, err := version.NewVersion(c)
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
@@ -0,0 +1,117 @@
+package tfschema
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	version "github.com/hashicorp/go-version"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+)
+
+func TestSchemaCacheRoundTrip(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	cache, err := NewSchemaCache(t.TempDir())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	src := "registry.terraform.io/hashicorp/null"
+	pV := version.Must(version.NewVersion("3.2.1"))
+	tfV := version.Must(version.NewVersion("1.3.6"))
+	schema := loadNullSchema(g)
+
+	lock := &ProviderLock{Src: src, Version: "3.2.1", Hashes: []string{"h1:abc="}}
+
+	_, hit, err := cache.Get(src, pV, tfV)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hit).To(BeFalse())
+
+	g.Expect(cache.Put(lock, tfV, schema)).To(Succeed())
+
+	cached, hit, err := cache.Get(src, pV, tfV)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hit).To(BeTrue())
+	g.Expect(cached.Schema.ResourceSchemas).To(HaveKey("null_resource"))
+	g.Expect(cached.Provider).To(Equal(lock))
+
+	// A different Terraform version should not hit the cache.
+	otherTFV := version.Must(version.NewVersion("1.4.0"))
+	_, hit, err = cache.Get(src, pV, otherTFV)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hit).To(BeFalse())
+
+	removed, err := cache.Prune(0)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(removed).To(Equal(1))
+
+	_, hit, err = cache.Get(src, pV, tfV)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hit).To(BeFalse())
+}
+
+func TestGetSchemasAllCached(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	cache, err := NewSchemaCache(t.TempDir())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	src := "registry.terraform.io/hashicorp/null"
+	tfV := version.Must(version.NewVersion("1.3.6"))
+	lock := &ProviderLock{Src: src, Version: "3.2.1", Hashes: []string{"h1:abc="}}
+	g.Expect(cache.Put(lock, tfV, loadNullSchema(g))).To(Succeed())
+
+	req, err := NewSchemaRequest("null", "=3.2.1")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Since all the providers are cached, this should not attempt to install or run Terraform.
+	logger := logging.GetSugaredLoggerForTest()
+	schemas, locks, err := GetSchemas(
+		logger, context.Background(), SchemaRequestList{req},
+		GetSchemasOpts{TerraformVersion: tfV, Cache: cache},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(schemas.Schemas).To(HaveKey(src))
+	g.Expect(locks).To(HaveKeyWithValue(src, lock))
+}
+
+func TestExactVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		constraint string
+		expected   string
+		isExact    bool
+	}{
+		{"3.2.1", "3.2.1", true},
+		{"=3.2.1", "3.2.1", true},
+		{"= 3.2.1", "3.2.1", true},
+		{"~>3.0", "", false},
+		{">=3.0, <4.0", "", false},
+		{"", "", false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.constraint, func(t *testing.T) {
+			t.Parallel()
+			g := NewGomegaWithT(t)
+
+			v, isExact := exactVersion(tc.constraint)
+			g.Expect(isExact).To(Equal(tc.isExact))
+			if tc.isExact {
+				g.Expect(v.String()).To(Equal(tc.expected))
+			}
+		})
+	}
+}
+
+func loadNullSchema(g *WithT) *tfjson.ProviderSchema {
+	logger := logging.GetSugaredLoggerForTest()
+	schemas, err := LoadSchemas(logger, []string{"fixtures/null_schema.json"})
+	g.Expect(err).NotTo(HaveOccurred())
+	return schemas.Schemas["registry.terraform.io/hashicorp/null"]
+}
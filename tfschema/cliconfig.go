@@ -0,0 +1,116 @@
+package tfschema
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	_ "embed"
+)
+
+const (
+	cliConfigFileName   = "libgenerator.tfrc"
+	cliConfigFileEnvVar = "TF_CLI_CONFIG_FILE"
+)
+
+var (
+	//go:embed cliconfig.tfrc.tmpl
+	cliConfigTmplContents string
+	cliConfigTmpl         = template.Must(
+		template.New("cliconfig").Funcs(template.FuncMap{
+			"quote":     strconv.Quote,
+			"quoteList": quoteList,
+		}).Parse(cliConfigTmplContents),
+	)
+)
+
+// ProviderInstallationConfig represents the CLI configuration for how the providers are installed when retrieving the
+// schemas. This is rendered into a CLI configuration file that is passed to the CLI with the TF_CLI_CONFIG_FILE
+// environment variable, which allows retrieving the schemas from an internal provider mirror.
+//
+// Refer to https://developer.hashicorp.com/terraform/cli/config/config-file#provider-installation for more info.
+type ProviderInstallationConfig struct {
+	// FilesystemMirrors is the list of local directories to search for providers.
+	FilesystemMirrors []FilesystemMirror
+
+	// NetworkMirrors is the list of network mirrors to download providers from.
+	NetworkMirrors []NetworkMirror
+
+	// Direct indicates whether providers can be installed directly from their origin registry. This only has an effect
+	// when there are mirrors configured, as the CLI always installs directly from the origin registry otherwise.
+	Direct bool
+
+	// PluginCacheDir is the directory to use as the shared provider plugin cache. In isolated mode (see
+	// GetSchemasOpts.Jobs), each workspace uses its own subdirectory of this directory instead, since the plugin cache
+	// is not safe for concurrent use.
+	PluginCacheDir string
+}
+
+// FilesystemMirror represents a filesystem_mirror provider installation method.
+type FilesystemMirror struct {
+	// Path is the path to the directory containing the mirrored providers.
+	Path string
+
+	// Include and Exclude are the provider source patterns that the mirror should (or should not) be used for.
+	Include []string
+	Exclude []string
+}
+
+// NetworkMirror represents a network_mirror provider installation method.
+type NetworkMirror struct {
+	// URL is the base URL of the provider network mirror. This must be an https URL ending with a slash.
+	URL string
+
+	// Include and Exclude are the provider source patterns that the mirror should (or should not) be used for.
+	Include []string
+	Exclude []string
+}
+
+// HasInstallationMethods returns whether the config has any explicit provider installation methods configured, in
+// which case a provider_installation block needs to be rendered.
+func (c *ProviderInstallationConfig) HasInstallationMethods() bool {
+	return len(c.FilesystemMirrors) > 0 || len(c.NetworkMirrors) > 0
+}
+
+// renderCLIConfig renders the CLI configuration file into the given working directory and returns the path to the
+// rendered file.
+func renderCLIConfig(wd string, cfg *ProviderInstallationConfig) (string, error) {
+	// The CLI silently ignores the plugin cache dir if it doesn't exist, so make sure it is available.
+	if cfg.PluginCacheDir != "" {
+		if err := os.MkdirAll(cfg.PluginCacheDir, 0755); err != nil {
+			return "", err
+		}
+	}
+
+	var out bytes.Buffer
+	if err := cliConfigTmpl.Execute(&out, cfg); err != nil {
+		return "", err
+	}
+
+	fpath := filepath.Join(wd, cliConfigFileName)
+	if err := os.WriteFile(fpath, out.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return fpath, nil
+}
+
+func quoteList(items []string) string {
+	quoted := make([]string, 0, len(items))
+	for _, i := range items {
+		quoted = append(quoted, strconv.Quote(i))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// envMap converts the given list of environment variables in KEY=VALUE form into a map.
+func envMap(environ []string) map[string]string {
+	env := map[string]string{}
+	for _, ev := range environ {
+		k, v, _ := strings.Cut(ev, "=")
+		env[k] = v
+	}
+	return env
+}
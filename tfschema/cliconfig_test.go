@@ -0,0 +1,55 @@
+package tfschema
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRenderCLIConfig(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	wd := t.TempDir()
+	pluginCacheDir := t.TempDir()
+	cfg := &ProviderInstallationConfig{
+		FilesystemMirrors: []FilesystemMirror{
+			{Path: "/opt/providers", Include: []string{"registry.terraform.io/hashicorp/*"}},
+		},
+		NetworkMirrors: []NetworkMirror{
+			{URL: "https://mirror.example.com/providers/", Exclude: []string{"registry.terraform.io/hashicorp/*"}},
+		},
+		PluginCacheDir: pluginCacheDir,
+	}
+
+	fpath, err := renderCLIConfig(wd, cfg)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	data, err := os.ReadFile(fpath)
+	g.Expect(err).NotTo(HaveOccurred())
+	rendered := string(data)
+	t.Log(rendered)
+
+	g.Expect(rendered).To(ContainSubstring(`plugin_cache_dir = "` + pluginCacheDir + `"`))
+	g.Expect(rendered).To(ContainSubstring("provider_installation {"))
+	g.Expect(rendered).To(ContainSubstring(`path = "/opt/providers"`))
+	g.Expect(rendered).To(ContainSubstring(`include = ["registry.terraform.io/hashicorp/*"]`))
+	g.Expect(rendered).To(ContainSubstring(`url = "https://mirror.example.com/providers/"`))
+	g.Expect(rendered).To(ContainSubstring(`exclude = ["registry.terraform.io/hashicorp/*"]`))
+	g.Expect(rendered).NotTo(ContainSubstring("direct"))
+}
+
+func TestRenderCLIConfigPluginCacheOnly(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	cfg := &ProviderInstallationConfig{PluginCacheDir: t.TempDir()}
+	fpath, err := renderCLIConfig(t.TempDir(), cfg)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	data, err := os.ReadFile(fpath)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(data)).To(ContainSubstring("plugin_cache_dir"))
+	g.Expect(string(data)).NotTo(ContainSubstring("provider_installation"))
+}
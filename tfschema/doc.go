@@ -1,3 +1,4 @@
 // Package tfschema contains routines for retrieving the schema info from Terraform and it's providers.
-// This primarily works by interacting with the terraform binary and using the `providers schema` command.
+// This primarily works by interacting with the terraform (or OpenTofu tofu) binary and using the `providers schema`
+// command.
 package tfschema
@@ -3,18 +3,17 @@ package tfschema
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "embed"
 
 	"github.com/google/go-jsonnet"
 	version "github.com/hashicorp/go-version"
-	install "github.com/hashicorp/hc-install"
-	"github.com/hashicorp/hc-install/fs"
-	"github.com/hashicorp/hc-install/product"
-	"github.com/hashicorp/hc-install/releases"
-	"github.com/hashicorp/hc-install/src"
 	"github.com/hashicorp/terraform-exec/tfexec"
 	tfjson "github.com/hashicorp/terraform-json"
 	tfaddr "github.com/hashicorp/terraform-registry-address"
@@ -24,6 +23,10 @@ import (
 const (
 	providersTFJsonnetName = "providers.tf.jsonnet"
 	providersTFJSONName    = "providers.tf.json"
+
+	// providerSchemasFormatVersion is the format version to report on provider schemas that are assembled entirely
+	// from the cache.
+	providerSchemasFormatVersion = "1.0"
 )
 
 var (
@@ -50,6 +53,13 @@ type SchemaRequest struct {
 // NewSchemaRequest constructs a new schema request given a canonical provider source string (e.g., aws or
 // DopplerHQ/doppler) and version constraint.
 func NewSchemaRequest(provider, version string) (*SchemaRequest, error) {
+	return NewSchemaRequestForBackend(BackendTerraform, provider, version)
+}
+
+// NewSchemaRequestForBackend constructs a new schema request for the given backend. Provider source strings that omit
+// the registry hostname resolve against the registry of the backend (e.g., aws resolves to
+// registry.opentofu.org/hashicorp/aws for OpenTofu).
+func NewSchemaRequestForBackend(backend Backend, provider, version string) (*SchemaRequest, error) {
 	pAddr, err := tfaddr.ParseProviderSource(provider)
 	if err != nil {
 		return nil, err
@@ -59,6 +69,18 @@ func NewSchemaRequest(provider, version string) (*SchemaRequest, error) {
 		pAddr.Namespace = "hashicorp"
 	}
 
+	// Source strings with a hostname have three parts (HOSTNAME/NAMESPACE/TYPE). Only override the hostname when it is
+	// omitted so that explicit registries are respected.
+	hasHostname := len(strings.Split(provider, "/")) == 3
+	if !hasHostname && backend.RegistryHost() != terraformRegistryHost {
+		pAddr, err = tfaddr.ParseProviderSource(
+			fmt.Sprintf("%s/%s/%s", backend.RegistryHost(), pAddr.Namespace, pAddr.Type),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &SchemaRequest{
 		Name:    pAddr.Type,
 		Src:     pAddr.String(),
@@ -66,27 +88,124 @@ func NewSchemaRequest(provider, version string) (*SchemaRequest, error) {
 	}, nil
 }
 
-// GetSchemas returns the resource and data source schemas for the requested providers using the Terraform binary and
-// the `providers get` command.
+// Key returns a string that uniquely identifies the request by the provider source and version constraint. This is
+// used to route the schemas to the requests when the same provider is requested at multiple versions.
+func (r *SchemaRequest) Key() string {
+	if r.Version == "" {
+		return r.Src
+	}
+	return fmt.Sprintf("%s@%s", r.Src, r.Version)
+}
+
+// GroupByVersion partitions the request list into batches where each provider name appears at most once. This is
+// necessary because a single Terraform module can only require one version of each provider (and the
+// required_providers block is keyed by the provider name), so requesting multiple versions of the same provider requires
+// retrieving the schemas across multiple workspaces. Duplicate requests (same source and version constraint) are
+// collapsed into one.
+func (l SchemaRequestList) GroupByVersion() []SchemaRequestList {
+	out := []SchemaRequestList{}
+	batchNames := []map[string]bool{}
+	seen := map[string]bool{}
+	for _, r := range l {
+		if seen[r.Key()] {
+			continue
+		}
+		seen[r.Key()] = true
+
+		placed := false
+		for i := range out {
+			if !batchNames[i][r.Name] {
+				out[i] = append(out[i], r)
+				batchNames[i][r.Name] = true
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			out = append(out, SchemaRequestList{r})
+			batchNames = append(batchNames, map[string]bool{r.Name: true})
+		}
+	}
+	return out
+}
+
+// GetSchemasOpts represents options for configuring how the provider schemas are retrieved.
+type GetSchemasOpts struct {
+	// Backend is the CLI tool to use for retrieving the providers and their schema.
+	Backend Backend
+
+	// TerraformVersion is the version of Terraform to use for retrieving the providers and their schema. Only used with
+	// the Terraform backend when TerraformPath is not set.
+	TerraformVersion *version.Version
+
+	// TerraformPath is the path to a preinstalled Terraform binary. Only used with the Terraform backend. When set,
+	// Terraform is not installed and the version of the binary is detected instead of using TerraformVersion.
+	TerraformPath string
+
+	// TofuPath is the path to the OpenTofu binary. Only used with the OpenTofu backend. When empty, the tofu binary is
+	// looked up on the PATH.
+	TofuPath string
+
+	// ProviderInstallation is the CLI configuration for how the providers are installed (e.g., from a filesystem or
+	// network mirror). When nil, the CLI configuration of the operator machine is used.
+	ProviderInstallation *ProviderInstallationConfig
+
+	// Jobs enables isolated mode when greater than 0, where each requested provider is retrieved in its own workspace.
+	// Jobs is the maximum number of workspaces that are processed concurrently. In isolated mode, a failure to retrieve
+	// the schema of one provider does not abort the others. When 0, all the providers are retrieved in a single
+	// workspace.
+	Jobs int
+
+	// Cache is the on-disk schema cache to use for looking up and storing the provider schemas. When nil, the cache is
+	// not used and the schemas are always retrieved from the providers.
+	Cache *SchemaCache
+}
+
+// GetSchemas returns the resource and data source schemas for the requested providers using the Terraform (or
+// OpenTofu) binary and the `providers schema` command.
 //
-// This function will look for a Terraform binary that matches the given requested version on the local machine. If it
-// cannot find one in the machine PATH, then this will install a new one in a temporary directory that is removed later.
+// With the Terraform backend, this function will look for a Terraform binary that matches the given requested version
+// on the local machine. If it cannot find one in the machine PATH, then this will install a new one in a temporary
+// directory that is removed later. If a TerraformPath is configured, that binary is used as is. With the OpenTofu
+// backend, this will use the tofu binary from the PATH, or the one
+// at the configured TofuPath.
 //
 // The providers are pulled down using `terraform init` against a basic Terraform module that only lists all the
 // requested providers as required_providers. We use Jsonnet to render this basic Terraform module given the schema
 // request. The module is rendered into a temporary directory that is cleaned up at the end of the function.
+//
+// When Jobs is set, each provider is retrieved in its own workspace, concurrently. In this mode, the schemas for the
+// providers that were successfully retrieved are returned even if some providers failed, alongside a *SchemaErrors
+// error that reports the failures for each provider.
+//
+// When a schema cache is configured, providers that are pinned to an exact version are first looked up in the cache.
+// If all the requested providers are in the cache, the providers are not initialized at all. The schemas that are
+// retrieved from the providers are stored in the cache, keyed by the version that the CLI resolved.
+//
+// Cancelling the context interrupts any running CLI commands. The temporary workspaces and any installed CLI binaries
+// are still removed before returning.
 func GetSchemas(
 	logger *zap.SugaredLogger,
 	ctx context.Context,
-	tfVersion *version.Version,
 	req SchemaRequestList,
-) (out *tfjson.ProviderSchemas, returnErr error) {
-	// Ensure Terraform binary is available.
-	inst := install.NewInstaller()
-	// Use an anon function so we handle the error for inst.Remove
+	opts GetSchemasOpts,
+) (out *tfjson.ProviderSchemas, locks ProviderLocks, returnErr error) {
+	if batches := req.GroupByVersion(); len(batches) > 1 {
+		return nil, nil, fmt.Errorf(
+			"multiple requests for providers named the same (e.g., the same provider at multiple versions): " +
+				"use GetSchemasByRequest to retrieve them",
+		)
+	}
+
+	backend, err := newSchemaBackend(logger, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Use an anon function so we handle the error for backend.cleanup. Note that this intentionally does not use ctx,
+	// so that the installed files are removed even when ctx is cancelled.
 	defer func() {
-		if err := inst.Remove(ctx); err != nil {
-			logger.Errorf("Error removing installed Terraform files: %s", err)
+		if err := backend.cleanup(context.Background()); err != nil {
+			logger.Errorf("Error removing installed %s files: %s", opts.Backend, err)
 
 			// Bubble remove error to the return error if an error hasn't been reported yet.
 			if returnErr == nil {
@@ -95,26 +214,181 @@ func GetSchemas(
 		}
 	}()
 
-	logger.Debugf("Finding or installing terraform version %s", tfVersion)
-	tfPath, err := inst.Ensure(ctx, []src.Source{
-		&fs.ExactVersion{
-			Product: product.Terraform,
-			Version: tfVersion,
-		},
-		&releases.ExactVersion{
-			Product: product.Terraform,
-			Version: tfVersion,
-		},
-	})
+	cliVersion, err := backend.version(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	cached, cachedLocks, misses, err := lookupCachedSchemas(logger, req, cliVersion, opts.Cache)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(misses) == 0 {
+		logger.Debug("All provider schemas were found in the cache")
+		return cached, cachedLocks, nil
+	}
+
+	execPath, err := backend.ensure(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
-	logger.Debugf("Using terraform binary %s", tfPath)
+	logger.Debugf("Using %s binary %s", opts.Backend, execPath)
+
+	var schemaErr error
+	if opts.Jobs > 0 {
+		out, locks, schemaErr = runProvidersSchemaIsolated(
+			logger, ctx, execPath, misses, opts.ProviderInstallation, opts.Jobs,
+		)
+		// Only abort on errors that are not reported per provider, so that the successful schemas are returned.
+		var perProviderErr *SchemaErrors
+		if schemaErr != nil && !errors.As(schemaErr, &perProviderErr) {
+			return nil, nil, schemaErr
+		}
+	} else {
+		out, locks, err = runProvidersSchema(logger, ctx, execPath, misses, opts.ProviderInstallation)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if opts.Cache != nil {
+		for src, schema := range out.Schemas {
+			lock, hasLock := locks[src]
+			if !hasLock || lock.Version == "" {
+				logger.Warnf("Could not determine resolved version of provider %s. Skipping cache", src)
+				continue
+			}
 
+			logger.Debugf("Storing schema for provider %s (%s) in cache", src, lock.Version)
+			if err := opts.Cache.Put(lock, cliVersion, schema); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	for src, schema := range cached.Schemas {
+		out.Schemas[src] = schema
+	}
+	for src, lock := range cachedLocks {
+		locks[src] = lock
+	}
+	return out, locks, schemaErr
+}
+
+// GetSchemasByRequest returns the schema for each of the requested providers, keyed by the request Key. Unlike
+// GetSchemas, this supports requesting the same provider at multiple versions by retrieving the schemas in batches
+// where each provider name appears at most once (see SchemaRequestList.GroupByVersion).
+//
+// The returned provider locks are also keyed by the request Key.
+//
+// Similar to GetSchemas, when Jobs is set the schemas that were successfully retrieved are returned alongside a
+// *SchemaErrors error that reports the failures for each request, keyed by the request Key.
+func GetSchemasByRequest(
+	logger *zap.SugaredLogger,
+	ctx context.Context,
+	req SchemaRequestList,
+	opts GetSchemasOpts,
+) (map[string]*tfjson.ProviderSchema, ProviderLocks, error) {
+	out := map[string]*tfjson.ProviderSchema{}
+	outLocks := ProviderLocks{}
+	allErrs := &SchemaErrors{Errors: map[string]error{}}
+
+	batches := req.GroupByVersion()
+	for i, batch := range batches {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		if len(batches) > 1 {
+			logger.Debugf("Retrieving schemas for batch %d of %d", i+1, len(batches))
+		}
+
+		schemas, locks, err := GetSchemas(logger, ctx, batch, opts)
+		var schemaErrs *SchemaErrors
+		if errors.As(err, &schemaErrs) {
+			for k, pErr := range schemaErrs.Errors {
+				allErrs.Errors[k] = pErr
+			}
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		for _, r := range batch {
+			if schema, hasSchema := schemas.Schemas[r.Src]; hasSchema {
+				out[r.Key()] = schema
+			}
+			if lock, hasLock := locks[r.Src]; hasLock {
+				outLocks[r.Key()] = lock
+			}
+		}
+	}
+
+	if len(allErrs.Errors) > 0 {
+		return out, outLocks, allErrs
+	}
+	return out, outLocks, nil
+}
+
+// lookupCachedSchemas looks up the requested provider schemas in the schema cache. This returns the schemas (and
+// provider locks) that were found in the cache, and the list of requests that need to be retrieved from the providers.
+func lookupCachedSchemas(
+	logger *zap.SugaredLogger,
+	req SchemaRequestList,
+	cliVersion *version.Version,
+	cache *SchemaCache,
+) (*tfjson.ProviderSchemas, ProviderLocks, SchemaRequestList, error) {
+	out := &tfjson.ProviderSchemas{
+		FormatVersion: providerSchemasFormatVersion,
+		Schemas:       map[string]*tfjson.ProviderSchema{},
+	}
+	locks := ProviderLocks{}
+	if cache == nil {
+		return out, locks, req, nil
+	}
+
+	misses := SchemaRequestList{}
+	for _, r := range req {
+		pV, isExact := exactVersion(r.Version)
+		if !isExact {
+			logger.Debugf("Version constraint %q for provider %s is not exact. Skipping cache lookup", r.Version, r.Src)
+			misses = append(misses, r)
+			continue
+		}
+
+		entry, hit, err := cache.Get(r.Src, pV, cliVersion)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !hit {
+			logger.Debugf("Cache miss for provider %s (%s)", r.Src, pV)
+			misses = append(misses, r)
+			continue
+		}
+
+		logger.Debugf("Cache hit for provider %s (%s)", r.Src, pV)
+		out.Schemas[r.Src] = entry.Schema
+		if entry.Provider != nil {
+			locks[r.Src] = entry.Provider
+		}
+	}
+	return out, locks, misses, nil
+}
+
+// runProvidersSchema retrieves the provider schemas by running init and providers schema with the given CLI binary
+// against a throwaway module that requires all the requested providers. This also returns the lock information
+// (resolved version and hashes) of the providers, as recorded in the dependency lock file during the init call. If a
+// provider installation config is provided, this is rendered as a CLI configuration file in the workspace and passed
+// to the CLI.
+func runProvidersSchema(
+	logger *zap.SugaredLogger,
+	ctx context.Context,
+	execPath string,
+	req SchemaRequestList,
+	installCfg *ProviderInstallationConfig,
+) (*tfjson.ProviderSchemas, ProviderLocks, error) {
 	// Create a temporary directory to use as a workspace
 	tmpDir, err := os.MkdirTemp("", "libgenerator-tf-*")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer os.RemoveAll(tmpDir)
 	logger.Debugf("Using working directory %s", tmpDir)
@@ -122,29 +396,59 @@ func GetSchemas(
 	// Render the providers.tf.json into the working dir
 	renderErr := renderProvidersTFJSON(ctx, tmpDir, req)
 	if renderErr != nil {
-		return nil, renderErr
+		return nil, nil, renderErr
 	}
 
 	logger.Debug("Rendered providers.tf.json:")
 	data, err := os.ReadFile(filepath.Join(tmpDir, providersTFJSONName))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	logger.Debug(string(data))
 
 	// Download the providers and extract the schemas
-	tf, err := tfexec.NewTerraform(tmpDir, tfPath)
+	tf, err := tfexec.NewTerraform(tmpDir, execPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if installCfg != nil {
+		cliCfgPath, err := renderCLIConfig(tmpDir, installCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.Debugf("Using CLI configuration file %s", cliCfgPath)
+
+		env := tfexec.CleanEnv(envMap(os.Environ()))
+		env[cliConfigFileEnvVar] = cliCfgPath
+		if err := tf.SetEnv(env); err != nil {
+			return nil, nil, err
+		}
 	}
-	logger.Debug("Running terraform init")
+	logger.Debug("Running init")
 	initErr := tf.Init(ctx)
 	if initErr != nil {
-		return nil, initErr
+		return nil, nil, initErr
+	}
+
+	logger.Debug("Reading dependency lock file to determine resolved provider versions")
+	locks, err := readDependencyLockFile(tmpDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Versions of the CLI that predate the dependency lock file do not report the resolved versions.
+		logger.Warnf("Could not find %s in the workspace. Resolved provider versions will not be reported", dependencyLockFileName)
+		locks = ProviderLocks{}
+	} else if err != nil {
+		return nil, nil, err
+	}
+	for src, lock := range locks {
+		logger.Debugf("Resolved provider %s to version %s", src, lock.Version)
 	}
 
-	logger.Debug("Running terraform providers schema")
-	return tf.ProvidersSchema(ctx)
+	logger.Debug("Running providers schema")
+	out, err := tf.ProvidersSchema(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, locks, nil
 }
 
 // renderProvidersTFJSON runs Jsonnet against the builtin providers.tf.jsonnet code to render a providers.tf.json file
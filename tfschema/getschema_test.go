@@ -2,6 +2,9 @@ package tfschema
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -54,7 +57,99 @@ func TestGetSchemasMultiple(t *testing.T) {
 	)
 }
 
+func TestGetSchemasFilesystemMirror(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	// Populate a plugin cache directory from the registry first. The plugin cache uses the same unpacked layout as a
+	// filesystem mirror, so it can be used as a local directory mirror in the next call.
+	mirrorDir := t.TempDir()
+	runGetSchemasWithOpts(
+		g,
+		[]providerReq{{"null", "=3.2.1"}},
+		[]string{"registry.terraform.io/hashicorp/null"},
+		GetSchemasOpts{
+			ProviderInstallation: &ProviderInstallationConfig{PluginCacheDir: mirrorDir},
+		},
+	)
+
+	// Now retrieve the schema again, this time only allowing installation from the local directory mirror.
+	runGetSchemasWithOpts(
+		g,
+		[]providerReq{{"null", "=3.2.1"}},
+		[]string{"registry.terraform.io/hashicorp/null"},
+		GetSchemasOpts{
+			ProviderInstallation: &ProviderInstallationConfig{
+				FilesystemMirrors: []FilesystemMirror{{Path: mirrorDir}},
+			},
+		},
+	)
+}
+
+func TestGroupByVersion(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	reqL := SchemaRequestList{}
+	for _, pr := range []providerReq{
+		{"aws", "~>4.0"},
+		{"aws", "~>5.0"},
+		{"null", "~>3.0"},
+		{"aws", "~>4.0"},
+	} {
+		req, err := NewSchemaRequest(pr.p, pr.v)
+		g.Expect(err).NotTo(HaveOccurred())
+		reqL = append(reqL, req)
+	}
+
+	batches := reqL.GroupByVersion()
+	g.Expect(batches).To(HaveLen(2))
+	g.Expect(batches[0]).To(HaveLen(2))
+	g.Expect(batches[0][0].Key()).To(Equal("registry.terraform.io/hashicorp/aws@~>4.0"))
+	g.Expect(batches[0][1].Key()).To(Equal("registry.terraform.io/hashicorp/null@~>3.0"))
+	g.Expect(batches[1]).To(HaveLen(1))
+	g.Expect(batches[1][0].Key()).To(Equal("registry.terraform.io/hashicorp/aws@~>5.0"))
+}
+
+func TestGetSchemasByRequestMultipleVersions(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	fixturesDir, err := filepath.Abs("fixtures")
+	g.Expect(err).NotTo(HaveOccurred())
+	fakeTFPath := filepath.Join(t.TempDir(), "terraform")
+	g.Expect(
+		os.WriteFile(fakeTFPath, []byte(fmt.Sprintf(fakeTFScript, fixturesDir)), 0755),
+	).To(Succeed())
+
+	reqL := SchemaRequestList{}
+	for _, v := range []string{"~>2.0", "~>3.0"} {
+		req, err := NewSchemaRequest("null", v)
+		g.Expect(err).NotTo(HaveOccurred())
+		reqL = append(reqL, req)
+	}
+
+	logger := logging.GetSugaredLoggerForTest()
+	ctx := context.Background()
+	opts := GetSchemasOpts{TerraformPath: fakeTFPath}
+
+	_, _, err = GetSchemas(logger, ctx, reqL, opts)
+	g.Expect(err).To(HaveOccurred())
+
+	schemas, locks, err := GetSchemasByRequest(logger, ctx, reqL, opts)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(schemas).To(HaveLen(2))
+	g.Expect(schemas).To(HaveKey("registry.terraform.io/hashicorp/null@~>2.0"))
+	g.Expect(schemas).To(HaveKey("registry.terraform.io/hashicorp/null@~>3.0"))
+	g.Expect(locks).To(HaveKey("registry.terraform.io/hashicorp/null@~>2.0"))
+	g.Expect(locks).To(HaveKey("registry.terraform.io/hashicorp/null@~>3.0"))
+}
+
 func runGetSchemas(g *WithT, providerReqs []providerReq, expectedKeys []string) {
+	runGetSchemasWithOpts(g, providerReqs, expectedKeys, GetSchemasOpts{})
+}
+
+func runGetSchemasWithOpts(g *WithT, providerReqs []providerReq, expectedKeys []string, opts GetSchemasOpts) {
 	reqL := SchemaRequestList{}
 	for _, pr := range providerReqs {
 		req, err := NewSchemaRequest(pr.p, pr.v)
@@ -64,11 +159,12 @@ func runGetSchemas(g *WithT, providerReqs []providerReq, expectedKeys []string)
 
 	logger := logging.GetSugaredLoggerForTest()
 	ctx := context.Background()
-	tfV := version.Must(version.NewVersion("1.3.6"))
-	schemas, err := GetSchemas(logger, ctx, tfV, reqL)
+	opts.TerraformVersion = version.Must(version.NewVersion("1.3.6"))
+	schemas, locks, err := GetSchemas(logger, ctx, reqL, opts)
 	g.Expect(err).NotTo(HaveOccurred())
 
 	for _, k := range expectedKeys {
 		g.Expect(schemas.Schemas).To(HaveKey(k))
+		g.Expect(locks).To(HaveKey(k))
 	}
 }
@@ -0,0 +1,47 @@
+package tfschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"go.uber.org/zap"
+)
+
+// LoadSchemas reads the provider schemas from the given list of files. Each file must contain the JSON output of
+// either `libgenerator getschema` or `terraform providers schema -json`. This is useful for generating libraries in
+// environments where Terraform can not be installed or the providers can not be downloaded.
+//
+// The schemas from all the files are merged together into a single ProviderSchemas object. If the same provider is
+// defined in multiple files, the schema from the file that appears later in the list takes precedence.
+func LoadSchemas(logger *zap.SugaredLogger, paths []string) (*tfjson.ProviderSchemas, error) {
+	out := &tfjson.ProviderSchemas{
+		Schemas: map[string]*tfjson.ProviderSchema{},
+	}
+
+	for _, p := range paths {
+		logger.Debugf("Loading provider schemas from %s", p)
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		var schemas tfjson.ProviderSchemas
+		if err := json.Unmarshal(data, &schemas); err != nil {
+			return nil, fmt.Errorf("error parsing provider schemas from %s: %w", p, err)
+		}
+
+		if out.FormatVersion == "" {
+			out.FormatVersion = schemas.FormatVersion
+		}
+		for src, schema := range schemas.Schemas {
+			if _, exists := out.Schemas[src]; exists {
+				logger.Warnf("Provider %s is defined in multiple schema files. Using the schema from %s", src, p)
+			}
+			out.Schemas[src] = schema
+		}
+	}
+
+	return out, nil
+}
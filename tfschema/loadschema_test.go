@@ -0,0 +1,36 @@
+package tfschema
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+)
+
+func TestLoadSchemasMultipleFiles(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	logger := logging.GetSugaredLoggerForTest()
+	schemas, err := LoadSchemas(logger, []string{
+		"fixtures/null_schema.json",
+		"fixtures/doppler_schema.json",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(schemas.FormatVersion).To(Equal("1.0"))
+	g.Expect(schemas.Schemas).To(HaveKey("registry.terraform.io/hashicorp/null"))
+	g.Expect(schemas.Schemas).To(HaveKey("registry.terraform.io/dopplerhq/doppler"))
+
+	nullSchema := schemas.Schemas["registry.terraform.io/hashicorp/null"]
+	g.Expect(nullSchema.ResourceSchemas).To(HaveKey("null_resource"))
+}
+
+func TestLoadSchemasMissingFile(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	logger := logging.GetSugaredLoggerForTest()
+	_, err := LoadSchemas(logger, []string{"fixtures/does_not_exist.json"})
+	g.Expect(err).To(HaveOccurred())
+}
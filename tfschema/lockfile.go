@@ -0,0 +1,115 @@
+package tfschema
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	dependencyLockFileName = ".terraform.lock.hcl"
+)
+
+var (
+	lockProviderBlockRe = regexp.MustCompile(`^provider\s+"([^"]+)"\s*\{$`)
+	lockStringAttrRe    = regexp.MustCompile(`^(\w+)\s*=\s*"([^"]*)"$`)
+	lockHashesStartRe   = regexp.MustCompile(`^hashes\s*=\s*\[$`)
+	lockHashRe          = regexp.MustCompile(`^"([^"]+)",?$`)
+)
+
+// ProviderLocks maps the provider source to the lock information of the provider.
+type ProviderLocks map[string]*ProviderLock
+
+// ProviderLock represents the resolved version and package hashes of a provider, as recorded in the dependency lock
+// file (.terraform.lock.hcl) after the providers are initialized.
+type ProviderLock struct {
+	// Src is the fully qualified provider source (e.g., registry.terraform.io/hashicorp/null).
+	Src string `json:"src"`
+
+	// Version is the exact version of the provider that was selected.
+	Version string `json:"version"`
+
+	// Constraints is the version constraint that was used to select the version.
+	Constraints string `json:"constraints,omitempty"`
+
+	// Hashes is the list of package checksums for the selected version.
+	Hashes []string `json:"hashes,omitempty"`
+}
+
+// readDependencyLockFile reads and parses the dependency lock file in the given working directory.
+func readDependencyLockFile(wd string) (ProviderLocks, error) {
+	data, err := os.ReadFile(filepath.Join(wd, dependencyLockFileName))
+	if err != nil {
+		return nil, err
+	}
+	return parseDependencyLockFile(data)
+}
+
+// parseDependencyLockFile parses the contents of a dependency lock file. The lock file is always generated by the CLI
+// in a canonical format, so this only handles the subset of HCL that the CLI emits rather than pulling in a full HCL
+// parser.
+func parseDependencyLockFile(data []byte) (ProviderLocks, error) {
+	out := ProviderLocks{}
+
+	var current *ProviderLock
+	inHashes := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		switch {
+		case current == nil:
+			m := lockProviderBlockRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("%s:%d: expected provider block", dependencyLockFileName, lineNum)
+			}
+			current = &ProviderLock{Src: m[1]}
+
+		case inHashes:
+			if line == "]" {
+				inHashes = false
+				continue
+			}
+			m := lockHashRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("%s:%d: expected hash string", dependencyLockFileName, lineNum)
+			}
+			current.Hashes = append(current.Hashes, m[1])
+
+		case line == "}":
+			out[current.Src] = current
+			current = nil
+
+		case lockHashesStartRe.MatchString(line):
+			inHashes = true
+
+		default:
+			m := lockStringAttrRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("%s:%d: unexpected line in provider block", dependencyLockFileName, lineNum)
+			}
+			switch m[1] {
+			case "version":
+				current.Version = m[2]
+			case "constraints":
+				current.Constraints = m[2]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		return nil, fmt.Errorf("%s: unterminated provider block for %s", dependencyLockFileName, current.Src)
+	}
+	return out, nil
+}
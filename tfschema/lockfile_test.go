@@ -0,0 +1,62 @@
+package tfschema
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const testDependencyLockFile = `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+
+provider "registry.terraform.io/hashicorp/null" {
+  version     = "3.2.1"
+  constraints = "~> 3.0"
+  hashes = [
+    "h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+    "zh:58ed64389620cc7b82f01332e27723856422820cfd302e304b5f6c3436fb9840",
+  ]
+}
+
+provider "registry.terraform.io/dopplerhq/doppler" {
+  version = "1.1.6"
+  hashes = [
+    "h1:jSyvkgsgxdxqmb3YjMbCcA/3AK0W8WyDmI6Tz8vvGeU=",
+  ]
+}
+`
+
+func TestParseDependencyLockFile(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	locks, err := parseDependencyLockFile([]byte(testDependencyLockFile))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(locks).To(HaveLen(2))
+
+	g.Expect(locks["registry.terraform.io/hashicorp/null"]).To(Equal(&ProviderLock{
+		Src:         "registry.terraform.io/hashicorp/null",
+		Version:     "3.2.1",
+		Constraints: "~> 3.0",
+		Hashes: []string{
+			"h1:FbGfc+muBsC17Ohy5g806iuI1hQc4SIexpYCrQHQd8w=",
+			"zh:58ed64389620cc7b82f01332e27723856422820cfd302e304b5f6c3436fb9840",
+		},
+	}))
+	g.Expect(locks["registry.terraform.io/dopplerhq/doppler"]).To(Equal(&ProviderLock{
+		Src:     "registry.terraform.io/dopplerhq/doppler",
+		Version: "1.1.6",
+		Hashes:  []string{"h1:jSyvkgsgxdxqmb3YjMbCcA/3AK0W8WyDmI6Tz8vvGeU="},
+	}))
+}
+
+func TestParseDependencyLockFileInvalid(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	_, err := parseDependencyLockFile([]byte(`provider "registry.terraform.io/hashicorp/null" {`))
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = parseDependencyLockFile([]byte(`resource "null_resource" "foo" {}`))
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,128 @@
+package tfschema
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"go.uber.org/zap"
+)
+
+// SchemaErrors is returned by GetSchemas when retrieving the schemas in isolated mode, and some of the providers failed.
+// The schemas for the providers that succeeded are still returned alongside the error.
+type SchemaErrors struct {
+	// Errors maps the request Key to the error that occurred while retrieving the schema of the requested provider.
+	Errors map[string]error
+}
+
+func (e *SchemaErrors) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for k := range e.Errors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", k, e.Errors[k]))
+	}
+	return fmt.Sprintf(
+		"failed to retrieve schemas for %d provider(s): %s",
+		len(keys), strings.Join(msgs, "; "),
+	)
+}
+
+// runProvidersSchemaIsolated retrieves the provider schemas by running runProvidersSchema for each requested provider
+// in its own workspace. Up to jobs workspaces are processed concurrently. Unlike runProvidersSchema, a failure in one
+// provider does not prevent retrieving the others: the schemas (and provider locks) for the providers that succeeded are
+// merged together and returned alongside a SchemaErrors error reporting the providers that failed. If ctx is cancelled,
+// the providers that have not started yet are skipped and the context error is returned.
+//
+// Each workspace uses its own plugin cache directory (see isolatedInstallationConfig), since the plugin cache is not
+// safe for concurrent use by multiple CLI processes.
+func runProvidersSchemaIsolated(
+	logger *zap.SugaredLogger,
+	ctx context.Context,
+	execPath string,
+	req SchemaRequestList,
+	installCfg *ProviderInstallationConfig,
+	jobs int,
+) (*tfjson.ProviderSchemas, ProviderLocks, error) {
+	out := &tfjson.ProviderSchemas{
+		FormatVersion: providerSchemasFormatVersion,
+		Schemas:       map[string]*tfjson.ProviderSchema{},
+	}
+	locks := ProviderLocks{}
+	schemaErrs := &SchemaErrors{Errors: map[string]error{}}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, jobs)
+	for _, r := range req {
+		r := r
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			pLogger := logger.With("provider", r.Src)
+			pLogger.Debug("Retrieving schema in isolated workspace")
+			schemas, pLocks, err := runProvidersSchema(
+				pLogger, ctx, execPath, SchemaRequestList{r}, isolatedInstallationConfig(installCfg, r),
+			)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				pLogger.Errorf("Error retrieving schema: %s", err)
+				schemaErrs.Errors[r.Key()] = err
+				return
+			}
+			if schemas.FormatVersion != "" {
+				out.FormatVersion = schemas.FormatVersion
+			}
+			for src, schema := range schemas.Schemas {
+				out.Schemas[src] = schema
+			}
+			for src, lock := range pLocks {
+				locks[src] = lock
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(schemaErrs.Errors) > 0 {
+		return out, locks, schemaErrs
+	}
+	return out, locks, nil
+}
+
+// isolatedInstallationConfig returns the provider installation config to use for the isolated workspace of the given
+// request. When a plugin cache directory is configured, the workspace uses a subdirectory of it that is derived from
+// the request Key, so that concurrent workspaces never share a plugin cache directory, while the same request reuses
+// the same cache across runs.
+func isolatedInstallationConfig(installCfg *ProviderInstallationConfig, r *SchemaRequest) *ProviderInstallationConfig {
+	if installCfg == nil || installCfg.PluginCacheDir == "" {
+		return installCfg
+	}
+
+	sum := sha256.Sum256([]byte(r.Key()))
+	cfg := *installCfg
+	cfg.PluginCacheDir = filepath.Join(installCfg.PluginCacheDir, hex.EncodeToString(sum[:]))
+	return &cfg
+}
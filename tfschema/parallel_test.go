@@ -0,0 +1,167 @@
+package tfschema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/tf-libsonnet/libgenerator/internal/logging"
+)
+
+// fakeTFScript is a fake terraform binary that fails to init any workspace requiring the broken provider, and
+// otherwise writes a dependency lock file and outputs the schema fixtures.
+const fakeTFScript = `#!/bin/sh
+case "$1" in
+  version)
+    echo '{"terraform_version": "1.5.7", "provider_selections": {}}'
+    ;;
+  init)
+    if grep -q broken providers.tf.json; then
+      echo "could not find provider broken" >&2
+      exit 1
+    fi
+    if grep -q doppler providers.tf.json; then
+      printf 'provider "registry.terraform.io/dopplerhq/doppler" {\n  version = "1.1.6"\n  hashes = [\n    "h1:doppler=",\n  ]\n}\n' > .terraform.lock.hcl
+    else
+      printf 'provider "registry.terraform.io/hashicorp/null" {\n  version = "3.2.1"\n  hashes = [\n    "h1:null=",\n  ]\n}\n' > .terraform.lock.hcl
+    fi
+    ;;
+  providers)
+    if grep -q doppler providers.tf.json; then
+      cat %[1]s/doppler_schema.json
+    else
+      cat %[1]s/null_schema.json
+    fi
+    ;;
+esac
+`
+
+func TestGetSchemasIsolatedPartialFailure(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	fixturesDir, err := filepath.Abs("fixtures")
+	g.Expect(err).NotTo(HaveOccurred())
+	fakeTFPath := filepath.Join(t.TempDir(), "terraform")
+	g.Expect(
+		os.WriteFile(fakeTFPath, []byte(fmt.Sprintf(fakeTFScript, fixturesDir)), 0755),
+	).To(Succeed())
+
+	reqL := SchemaRequestList{}
+	for _, p := range []string{"null", "DopplerHQ/doppler", "broken"} {
+		req, err := NewSchemaRequest(p, "")
+		g.Expect(err).NotTo(HaveOccurred())
+		reqL = append(reqL, req)
+	}
+
+	logger := logging.GetSugaredLoggerForTest()
+	schemas, locks, err := GetSchemas(
+		logger, context.Background(), reqL,
+		GetSchemasOpts{TerraformPath: fakeTFPath, Jobs: 2},
+	)
+
+	var schemaErrs *SchemaErrors
+	g.Expect(errors.As(err, &schemaErrs)).To(BeTrue())
+	g.Expect(schemaErrs.Errors).To(HaveLen(1))
+	g.Expect(schemaErrs.Errors).To(HaveKey("registry.terraform.io/hashicorp/broken"))
+
+	g.Expect(schemas.Schemas).To(HaveLen(2))
+	g.Expect(schemas.Schemas).To(HaveKey("registry.terraform.io/hashicorp/null"))
+	g.Expect(schemas.Schemas).To(HaveKey("registry.terraform.io/dopplerhq/doppler"))
+
+	g.Expect(locks).To(HaveLen(2))
+	g.Expect(locks["registry.terraform.io/hashicorp/null"].Version).To(Equal("3.2.1"))
+	g.Expect(locks["registry.terraform.io/dopplerhq/doppler"].Hashes).To(Equal([]string{"h1:doppler="}))
+}
+
+func TestGetSchemasIsolatedCancelled(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	fixturesDir, err := filepath.Abs("fixtures")
+	g.Expect(err).NotTo(HaveOccurred())
+	fakeTFPath := filepath.Join(t.TempDir(), "terraform")
+	g.Expect(
+		os.WriteFile(fakeTFPath, []byte(fmt.Sprintf(fakeTFScript, fixturesDir)), 0755),
+	).To(Succeed())
+
+	req, err := NewSchemaRequest("null", "")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	logger := logging.GetSugaredLoggerForTest()
+	_, _, err = GetSchemas(
+		logger, ctx, SchemaRequestList{req},
+		GetSchemasOpts{TerraformPath: fakeTFPath, Jobs: 2},
+	)
+	g.Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+}
+
+func TestIsolatedInstallationConfig(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	nullReq, err := NewSchemaRequest("null", "~>3.0")
+	g.Expect(err).NotTo(HaveOccurred())
+	otherNullReq, err := NewSchemaRequest("null", "~>2.0")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(isolatedInstallationConfig(nil, nullReq)).To(BeNil())
+	noCache := &ProviderInstallationConfig{Direct: true}
+	g.Expect(isolatedInstallationConfig(noCache, nullReq)).To(BeIdenticalTo(noCache))
+
+	installCfg := &ProviderInstallationConfig{Direct: true, PluginCacheDir: "/tmp/plugin-cache"}
+	nullCfg := isolatedInstallationConfig(installCfg, nullReq)
+	otherNullCfg := isolatedInstallationConfig(installCfg, otherNullReq)
+	g.Expect(nullCfg.Direct).To(BeTrue())
+	g.Expect(filepath.Dir(nullCfg.PluginCacheDir)).To(Equal("/tmp/plugin-cache"))
+	g.Expect(filepath.Dir(otherNullCfg.PluginCacheDir)).To(Equal("/tmp/plugin-cache"))
+	g.Expect(nullCfg.PluginCacheDir).NotTo(Equal(otherNullCfg.PluginCacheDir))
+	g.Expect(installCfg.PluginCacheDir).To(Equal("/tmp/plugin-cache"))
+
+	// The same request should always reuse the same cache directory.
+	g.Expect(isolatedInstallationConfig(installCfg, nullReq)).To(Equal(nullCfg))
+}
+
+func TestGetSchemasIsolatedPluginCacheDir(t *testing.T) {
+	t.Parallel()
+	g := NewGomegaWithT(t)
+
+	fixturesDir, err := filepath.Abs("fixtures")
+	g.Expect(err).NotTo(HaveOccurred())
+	fakeTFPath := filepath.Join(t.TempDir(), "terraform")
+	g.Expect(
+		os.WriteFile(fakeTFPath, []byte(fmt.Sprintf(fakeTFScript, fixturesDir)), 0755),
+	).To(Succeed())
+
+	reqL := SchemaRequestList{}
+	for _, p := range []string{"null", "DopplerHQ/doppler"} {
+		req, err := NewSchemaRequest(p, "")
+		g.Expect(err).NotTo(HaveOccurred())
+		reqL = append(reqL, req)
+	}
+
+	pluginCacheDir := t.TempDir()
+	installCfg := &ProviderInstallationConfig{PluginCacheDir: pluginCacheDir}
+	logger := logging.GetSugaredLoggerForTest()
+	_, _, err = GetSchemas(
+		logger, context.Background(), reqL,
+		GetSchemasOpts{TerraformPath: fakeTFPath, Jobs: 2, ProviderInstallation: installCfg},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Each workspace should get its own plugin cache directory.
+	entries, err := os.ReadDir(pluginCacheDir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(HaveLen(2))
+	for _, req := range reqL {
+		g.Expect(isolatedInstallationConfig(installCfg, req).PluginCacheDir).To(BeADirectory())
+	}
+}